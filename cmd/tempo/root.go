@@ -1,15 +1,23 @@
 package tempo
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/usetempo/tempo-cli/internal/config"
 	"github.com/usetempo/tempo-cli/internal/detector"
 	"github.com/usetempo/tempo-cli/internal/hooks"
+	"github.com/usetempo/tempo-cli/internal/notes"
 	"github.com/usetempo/tempo-cli/internal/sender"
 )
 
@@ -23,15 +31,26 @@ func Execute(version string) error {
 		Use:     "tempo-cli",
 		Short:   "AI code attribution for git commits",
 		Version: version,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			settings, _ := cmd.Flags().GetStringArray("config")
+			return config.SetFlagOverrides(settings)
+		},
 	}
+	rootCmd.PersistentFlags().StringArrayP("config", "c", nil, "Override a config key for this run (key=value, repeatable)")
 
 	rootCmd.AddCommand(
 		newEnableCmd(),
 		newDisableCmd(),
 		newAuthCmd(),
+		newConfigCmd(),
 		newStatusCmd(),
 		newTestCmd(),
+		newTrailersCmd(),
+		newNotesCmd(),
+		newAnnotateCmd(),
+		newPendingCmd(),
 		newDetectCmd(),
+		newPrepareCommitMsgCmd(),
 		newSyncCmd(),
 	)
 
@@ -39,7 +58,7 @@ func Execute(version string) error {
 }
 
 func newEnableCmd() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "enable",
 		Short: "Install git hooks for AI attribution detection",
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -52,14 +71,40 @@ func newEnableCmd() *cobra.Command {
 			}
 			fmt.Println("Tempo hooks installed successfully.")
 
-			cfg, _ := config.Load()
-			if cfg.APIToken == "" {
+			if mode, _ := cmd.Flags().GetString("trailers"); mode != "" {
+				if err := hooks.EnableTrailers(repoRoot, mode); err != nil {
+					return fmt.Errorf("enabling trailers: %w", err)
+				}
+				if mode == hooks.TrailersPreview {
+					fmt.Println("AI disclosure trailers enabled in preview mode (printed, not written).")
+				} else {
+					fmt.Println("AI disclosure trailers enabled.")
+				}
+			}
+			if notesFlag, _ := cmd.Flags().GetBool("notes"); notesFlag {
+				if err := notes.Enable(repoRoot); err != nil {
+					return fmt.Errorf("enabling notes: %w", err)
+				}
+				fmt.Printf("Attribution notes enabled (%s, pushed with your branches).\n", notes.Ref)
+			}
+
+			cfg, err := config.Load(repoRoot)
+			if err != nil {
+				fmt.Printf("Warning: %v\n", err)
+				fmt.Println("Fix the config file; detection and sync are skipped until then.")
+				return nil
+			}
+			if _, profile := cfg.ResolveProfile(detector.RemoteHostPath(repoRoot)); profile.TokenSource() == "" {
 				fmt.Println("Warning: No API token configured. Running in offline mode.")
 				fmt.Println("Run 'tempo-cli auth <token>' to connect to Tempo cloud.")
 			}
 			return nil
 		},
 	}
+	cmd.Flags().String("trailers", "", "Also add AI disclosure trailers to commit messages (\"on\" or \"preview\")")
+	cmd.Flags().Lookup("trailers").NoOptDefVal = hooks.TrailersOn
+	cmd.Flags().Bool("notes", false, "Also store attribution in git notes and push them with branches")
+	return cmd
 }
 
 func newDisableCmd() *cobra.Command {
@@ -74,6 +119,9 @@ func newDisableCmd() *cobra.Command {
 			if err := hooks.Uninstall(repoRoot); err != nil {
 				return fmt.Errorf("removing hooks: %w", err)
 			}
+			if err := notes.Disable(repoRoot); err != nil {
+				return fmt.Errorf("disabling notes: %w", err)
+			}
 			fmt.Println("Tempo hooks removed.")
 			return nil
 		},
@@ -84,31 +132,370 @@ func newAuthCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "auth <token>",
 		Short: "Configure API token for Tempo cloud",
-		Args:  cobra.ExactArgs(1),
+		Long: `Save an API token for Tempo cloud. The token is checked against the API
+before it is saved. "auth <token>" is short for "auth login <token>".`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return cmd.Help()
+			}
+			return authLogin(cmd, args[0])
+		},
+	}
+	addLoginFlags(cmd)
+	cmd.AddCommand(newAuthLoginCmd(), newAuthLogoutCmd(), newAuthStatusCmd())
+	return cmd
+}
+
+func newAuthLoginCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "login [token]",
+		Short: "Validate and save an API token (read from stdin if not given)",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var token string
+			if len(args) == 1 {
+				token = args[0]
+			} else {
+				if st, err := os.Stdin.Stat(); err == nil && st.Mode()&os.ModeCharDevice != 0 {
+					fmt.Fprint(os.Stderr, "API token: ")
+				}
+				line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+				if err != nil && line == "" {
+					return fmt.Errorf("reading token: %w", err)
+				}
+				token = strings.TrimSpace(line)
+			}
+			if token == "" {
+				return fmt.Errorf("no token given")
+			}
+			return authLogin(cmd, token)
+		},
+	}
+	addLoginFlags(cmd)
+	return cmd
+}
+
+func addLoginFlags(cmd *cobra.Command) {
+	cmd.Flags().String("endpoint", "", "API endpoint override")
+	cmd.Flags().String("profile", "", "Save to a named profile instead of the default account")
+	cmd.Flags().Bool("git-credential", false, "Store the token with your git credential helper instead of the config file")
+	cmd.Flags().Bool("no-verify", false, "Save the token without checking it against the API")
+}
+
+// authLogin validates token against the API and saves it.
+func authLogin(cmd *cobra.Command, token string) error {
+	endpoint, _ := cmd.Flags().GetString("endpoint")
+	profile, _ := cmd.Flags().GetString("profile")
+	gitCredential, _ := cmd.Flags().GetBool("git-credential")
+	noVerify, _ := cmd.Flags().GetBool("no-verify")
+	if profile == config.OfflineProfile {
+		return fmt.Errorf("%q is a reserved profile name", profile)
+	}
+
+	if !noVerify {
+		checkEndpoint := endpoint
+		if checkEndpoint == "" {
+			checkEndpoint = profileEndpoint(profile)
+		}
+		id, err := sender.Whoami(checkEndpoint, token, cliVersion)
+		if errors.Is(err, sender.ErrInvalidToken) {
+			return fmt.Errorf("%s rejected the token, not saving it", checkEndpoint)
+		}
+		if err != nil {
+			return fmt.Errorf("validating token: %w (use --no-verify to save it anyway)", err)
+		}
+		if id.Expired() {
+			return fmt.Errorf("token expired on %s, not saving it", id.ExpiresAt.Format("2006-01-02"))
+		}
+		fmt.Printf("Authenticated to %s.\n", identityName(id))
+	}
+
+	where, err := config.SaveToken(profile, endpoint, token, gitCredential)
+	if err != nil {
+		return fmt.Errorf("saving token: %w", err)
+	}
+	if profile != "" {
+		fmt.Printf("API token saved to profile %q (%s).\n", profile, where)
+	} else {
+		fmt.Printf("API token saved (%s).\n", where)
+	}
+	return nil
+}
+
+func newAuthLogoutCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "logout",
+		Short: "Remove a saved API token",
+		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			token := args[0]
-			endpoint, _ := cmd.Flags().GetString("endpoint")
+			profile, _ := cmd.Flags().GetString("profile")
+			removeProfile, _ := cmd.Flags().GetBool("remove-profile")
+			if removeProfile && (profile == "" || profile == config.DefaultProfile) {
+				return fmt.Errorf("--remove-profile needs --profile <name>")
+			}
+			if err := config.RemoveToken(profile, removeProfile); err != nil {
+				return fmt.Errorf("removing token: %w", err)
+			}
 
-			cfg, err := config.Load()
+			switch {
+			case removeProfile:
+				fmt.Printf("Profile %q removed.\n", profile)
+			case profile != "":
+				fmt.Printf("API token removed from profile %q.\n", profile)
+			default:
+				fmt.Println("API token removed.")
+			}
+			if source := profileSource(profile); source != "" {
+				fmt.Printf("Note: a token is still read from %s.\n", source)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().String("profile", "", "Log out of a named profile instead of the default account")
+	cmd.Flags().Bool("remove-profile", false, "Also delete the profile")
+	return cmd
+}
+
+func newAuthStatusCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Check the API token against Tempo cloud",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repoRoot, _ := gitRepoRoot()
+			cfg, err := config.Load(repoRoot)
+			if err != nil {
+				return err
+			}
+			if name, _ := cmd.Flags().GetString("profile"); name != "" {
+				cfg.Profile = name
+			}
+			name, profile := cfg.ResolveProfile(detector.RemoteHostPath(repoRoot))
+
+			fmt.Printf("Profile:   %s\n", name)
+			source := profile.TokenSource()
+			if source == "" {
+				fmt.Println("API token: not configured (offline mode)")
+				return nil
+			}
+			fmt.Printf("Endpoint:  %s\n", profile.Endpoint)
+			fmt.Printf("API token: from %s\n", source)
+
+			token, err := profile.Token()
 			if err != nil {
-				cfg = &config.Config{}
+				return fmt.Errorf("reading API token: %w", err)
 			}
-			cfg.APIToken = token
-			if endpoint != "" {
-				cfg.Endpoint = endpoint
+			if token == "" {
+				return fmt.Errorf("%s returned no token", source)
+			}
+			id, err := sender.Whoami(profile.Endpoint, token, cliVersion)
+			if errors.Is(err, sender.ErrInvalidToken) {
+				return fmt.Errorf("the API rejected the token; run 'tempo-cli auth login' to replace it")
+			}
+			if err != nil {
+				return fmt.Errorf("checking token: %w", err)
 			}
 
-			if err := config.Save(cfg); err != nil {
-				return fmt.Errorf("saving config: %w", err)
+			fmt.Printf("Org:       %s\n", id.Org)
+			if id.User != "" {
+				fmt.Printf("User:      %s\n", id.User)
+			}
+			if len(id.Scopes) > 0 {
+				fmt.Printf("Scopes:    %s\n", strings.Join(id.Scopes, ", "))
+			}
+			switch {
+			case id.ExpiresAt == nil:
+				fmt.Println("Expires:   never")
+			case id.Expired():
+				fmt.Printf("Expires:   expired %s\n", id.ExpiresAt.Local().Format("2006-01-02"))
+				return fmt.Errorf("token expired; run 'tempo-cli auth login' to replace it")
+			default:
+				days := int(time.Until(*id.ExpiresAt).Hours() / 24)
+				fmt.Printf("Expires:   %s (in %d days)\n", id.ExpiresAt.Local().Format("2006-01-02"), days)
 			}
-			fmt.Println("API token saved.")
 			return nil
 		},
 	}
-	cmd.Flags().String("endpoint", "", "API endpoint override")
+	cmd.Flags().String("profile", "", "Check a named profile instead of the one the repo resolves to")
+	return cmd
+}
+
+// profileEndpoint returns the endpoint a token saved to the named profile
+// is used with.
+func profileEndpoint(name string) string {
+	cfg, err := config.Load("")
+	if err != nil {
+		return ""
+	}
+	if p, ok := cfg.Profiles[name]; ok && p.Endpoint != "" {
+		return p.Endpoint
+	}
+	return cfg.Endpoint
+}
+
+// profileSource returns where the named profile still reads a token from,
+// or "" if it has none.
+func profileSource(name string) string {
+	cfg, err := config.Load("")
+	if err != nil {
+		return ""
+	}
+	if name == "" {
+		name = config.DefaultProfile
+	}
+	cfg.Profile = name
+	_, p := cfg.ResolveProfile("")
+	return p.TokenSource()
+}
+
+func identityName(id *sender.Identity) string {
+	if id.User != "" {
+		return fmt.Sprintf("%s as %s", id.Org, id.User)
+	}
+	return id.Org
+}
+
+func newConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect and change configuration",
+		Long: `Configuration is resolved from these layers, later ones winning:
+
+  system  /etc/tempo/config.json
+  user    ~/.tempo/config.json
+  repo    .tempo.json in the repo root (committed; may not hold api_token)
+  env     TEMPO_API_TOKEN, TEMPO_API_ENDPOINT, TEMPO_SESSION_MAX_AGE, TEMPO_PRIVACY
+  flag    tempo-cli -c key=value`,
+	}
+
+	get := &cobra.Command{
+		Use:   "get <key>",
+		Short: "Print the resolved value of a key",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repoRoot, _ := gitRepoRoot()
+			layers, err := config.Resolve(repoRoot)
+			if err != nil {
+				return err
+			}
+			raw, origin, ok := layers.Get(args[0])
+			if !ok {
+				if !slices.Contains(config.Keys(), args[0]) {
+					return fmt.Errorf("unknown config key %q", args[0])
+				}
+				return nil
+			}
+			if showOrigin, _ := cmd.Flags().GetBool("show-origin"); showOrigin {
+				fmt.Printf("%s\t", origin)
+			}
+			fmt.Println(configValue(raw))
+			return nil
+		},
+	}
+	get.Flags().Bool("show-origin", false, "Show which layer the value comes from")
+
+	set := &cobra.Command{
+		Use:   "set <key> <value>",
+		Short: "Set a key in the user config, or with --repo in .tempo.json",
+		Long: `Set a key in the user config, or with --repo in .tempo.json.
+
+Strings are given bare, lists of strings comma-separated or as JSON, and
+other values as JSON. An empty value removes the key.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			scope := config.ScopeUser
+			repoRoot := ""
+			if repo, _ := cmd.Flags().GetBool("repo"); repo {
+				var err error
+				if repoRoot, err = gitRepoRoot(); err != nil {
+					return fmt.Errorf("not a git repository")
+				}
+				scope = config.ScopeRepo
+			}
+			if system, _ := cmd.Flags().GetBool("system"); system {
+				scope = config.ScopeSystem
+			}
+
+			var raw json.RawMessage
+			if args[1] != "" {
+				var err error
+				if raw, err = config.ParseValue(args[0], args[1]); err != nil {
+					return err
+				}
+			} else if !slices.Contains(config.Keys(), args[0]) {
+				return fmt.Errorf("unknown config key %q", args[0])
+			}
+			return config.SetValue(scope, repoRoot, args[0], raw)
+		},
+	}
+	set.Flags().Bool("repo", false, "Write to the repo's .tempo.json")
+	set.Flags().Bool("system", false, "Write to the system config")
+	set.MarkFlagsMutuallyExclusive("repo", "system")
+
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "List all resolved settings",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repoRoot, _ := gitRepoRoot()
+			layers, err := config.Resolve(repoRoot)
+			if err != nil {
+				return err
+			}
+			showOrigin, _ := cmd.Flags().GetBool("show-origin")
+			for _, key := range layers.Defined() {
+				raw, origin, _ := layers.Get(key)
+				value := configValue(raw)
+				switch key {
+				case "api_token":
+					value = "********"
+				case "profiles":
+					value = maskProfileTokens(raw)
+				}
+				if showOrigin {
+					fmt.Printf("%s\t", origin)
+				}
+				fmt.Printf("%s=%s\n", key, value)
+			}
+			return nil
+		},
+	}
+	list.Flags().Bool("show-origin", false, "Show which layer each value comes from")
+
+	cmd.AddCommand(get, set, list)
 	return cmd
 }
 
+// maskProfileTokens formats the profiles value with tokens hidden.
+func maskProfileTokens(raw json.RawMessage) string {
+	var profiles map[string]config.Profile
+	if err := json.Unmarshal(raw, &profiles); err != nil {
+		return configValue(raw)
+	}
+	for name, p := range profiles {
+		if p.APIToken != "" {
+			p.APIToken = "********"
+			profiles[name] = p
+		}
+	}
+	data, _ := json.Marshal(profiles)
+	return string(data)
+}
+
+// configValue formats a config value for display: strings bare, anything
+// else as compact JSON.
+func configValue(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return string(raw)
+	}
+	return buf.String()
+}
+
 func newStatusCmd() *cobra.Command {
 	return &cobra.Command{
 		Use:   "status",
@@ -126,16 +513,81 @@ func newStatusCmd() *cobra.Command {
 				fmt.Println("Hooks:     not installed")
 			}
 
+			if _, err := os.Stat(filepath.Join(repoRoot, config.RepoFile)); err == nil {
+				fmt.Printf("Config:    %s (repo policy)\n", config.RepoFile)
+			}
+			fmt.Printf("Trailers:  %s\n", hooks.TrailerMode(repoRoot))
+			if notes.Enabled(repoRoot) {
+				fmt.Printf("Notes:     on (%s)\n", notes.Ref)
+			} else {
+				fmt.Println("Notes:     off")
+			}
+
 			// Pending
-			pending := sender.PendingCount(repoRoot)
-			fmt.Printf("Pending:   %d attribution records\n", pending)
+			store := sender.OpenStore(repoRoot)
+			fmt.Printf("Pending:   %d attribution records\n", store.Count())
+			if failed := store.FailedCount(); failed > 0 {
+				fmt.Printf("Failed:    %d records rejected by the API (see 'tempo-cli pending show')\n", failed)
+			}
+			if st, err := os.Stat(sender.SyncLogPath(repoRoot)); err == nil {
+				fmt.Printf("Sync log:  .tempo/sync.log (updated %s)\n", st.ModTime().Format("2006-01-02 15:04"))
+			}
 
-			// API token
-			cfg, _ := config.Load()
-			if cfg.APIToken != "" {
-				fmt.Println("API token: configured")
+			// Profile and API token
+			cfg, err := config.Load(repoRoot)
+			if err != nil {
+				fmt.Printf("Config:    %v (detection and sync are skipped)\n", err)
+				return nil
+			}
+			remote := detector.RemoteHostPath(repoRoot)
+			name, profile := cfg.ResolveProfile(remote)
+			if remote != "" {
+				fmt.Printf("Profile:   %s (remote %s)\n", name, remote)
 			} else {
+				fmt.Printf("Profile:   %s (no remote)\n", name)
+			}
+			switch {
+			case profile.TokenSource() != "":
+				fmt.Printf("API token: from %s (%s)\n", profile.TokenSource(), profile.Endpoint)
+			case name == config.OfflineProfile || name == config.DefaultProfile:
 				fmt.Println("API token: not configured (offline mode)")
+			default:
+				fmt.Printf("API token: not configured, no profile %q (offline mode)\n", name)
+			}
+
+			// Session roots
+			fmt.Println()
+			fmt.Println("Session roots:")
+			roots := detectOptions(cfg).SessionRoots
+			for _, tool := range roots.Tools() {
+				var found []string
+				for _, dir := range roots[tool] {
+					if _, err := os.Stat(dir); err == nil {
+						found = append(found, dir)
+					}
+				}
+				if len(found) == 0 {
+					fmt.Printf("  %-12s (none found)\n", tool)
+					continue
+				}
+				for i, dir := range found {
+					if i == 0 {
+						fmt.Printf("  %-12s %s\n", tool, dir)
+					} else {
+						fmt.Printf("  %-12s %s\n", "", dir)
+					}
+				}
+			}
+
+			// Custom trailer rules
+			if len(cfg.TrailerRules) > 0 {
+				fmt.Println()
+				fmt.Printf("Trailer rules: %d custom\n", len(cfg.TrailerRules))
+				for _, r := range cfg.TrailerRules {
+					if err := detector.TrailerRule(r).Validate(); err != nil {
+						fmt.Printf("  invalid: %v\n", err)
+					}
+				}
 			}
 
 			return nil
@@ -153,7 +605,11 @@ func newTestCmd() *cobra.Command {
 				return fmt.Errorf("not a git repository")
 			}
 
-			attr, err := detector.Detect(repoRoot)
+			cfg, err := config.Load(repoRoot)
+			if err != nil {
+				return err
+			}
+			attr, err := detector.Detect(repoRoot, detectOptions(cfg))
 			if err != nil {
 				return err
 			}
@@ -169,40 +625,7 @@ func newTestCmd() *cobra.Command {
 				return nil
 			}
 
-			// Human-readable output
-			fmt.Printf("Commit:  %s\n", attr.CommitSHA)
-			fmt.Printf("Author:  %s\n", attr.CommitAuthor)
-			if attr.Repo != "" {
-				fmt.Printf("Repo:    %s\n", attr.Repo)
-			}
-			fmt.Println()
-
-			for _, d := range attr.Detections {
-				icon := "\U0001f7e2" // green circle
-				if d.Confidence == detector.ConfidenceMedium {
-					icon = "\U0001f7e1" // yellow circle
-				}
-				fmt.Printf("%s  %s (%s confidence, %s)\n", icon, d.Tool, d.Confidence, d.Method)
-
-				if len(d.FilesMatched) > 0 {
-					fmt.Printf("   Files: %d/%d committed files matched\n", d.AIFiles, d.FilesCommitted)
-					for _, f := range d.FilesMatched {
-						fmt.Printf("     - %s\n", f)
-					}
-				}
-				if d.Model != "" {
-					fmt.Printf("   Model: %s\n", d.Model)
-				}
-				if d.TokenUsage > 0 {
-					fmt.Printf("   Tokens: %d\n", d.TokenUsage)
-				}
-				if d.SessionDurationSec > 0 {
-					mins := d.SessionDurationSec / 60
-					secs := d.SessionDurationSec % 60
-					fmt.Printf("   Session: %dm%ds\n", mins, secs)
-				}
-				fmt.Println()
-			}
+			printAttribution(attr)
 			return nil
 		},
 	}
@@ -219,29 +642,641 @@ func newDetectCmd() *cobra.Command {
 			if err != nil {
 				return nil
 			}
-			attr, err := detector.Detect(repoRoot)
+			// Defaults would drop settings like privacy and ignore, so an
+			// unreadable config records nothing
+			cfg, err := config.Load(repoRoot)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "tempo-cli: warning: %v, not recording this commit\n", err)
+				return nil
+			}
+			opts := detectOptions(cfg)
+			hook, _ := cmd.Flags().GetString("hook")
+			opts.InHook = hook != ""
+			attr, err := detector.Detect(repoRoot, opts)
 			if err != nil || attr == nil {
 				return nil
 			}
-			return sender.SavePending(repoRoot, attr)
+			if notes.Enabled(repoRoot) {
+				if err := notes.Write(repoRoot, attr); err != nil {
+					fmt.Fprintf(os.Stderr, "tempo-cli: warning: writing note: %v\n", err)
+				}
+			}
+			return sender.OpenStore(repoRoot).Put(sender.KindAttribution, attr)
 		},
 	}
 	cmd.Flags().String("hook", "", "hook type (internal)")
 	return cmd
 }
 
-func newSyncCmd() *cobra.Command {
+func newTrailersCmd() *cobra.Command {
 	return &cobra.Command{
-		Use:    "_sync",
+		Use:   "trailers",
+		Short: "Preview the AI disclosure trailers for the staged changes",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repoRoot, err := gitRepoRoot()
+			if err != nil {
+				return fmt.Errorf("not a git repository")
+			}
+			// Detect exactly as the hook would when committing from here
+			cfg, err := config.Load(repoRoot)
+			if err != nil {
+				return err
+			}
+			opts := detectOptions(cfg)
+			opts.Staged = true
+			opts.InHook = true
+			attr, err := detector.Detect(repoRoot, opts)
+			if err != nil {
+				return err
+			}
+			trailers := detector.DisclosureTrailers(attr)
+			if len(trailers) == 0 {
+				fmt.Println("No AI tool usage detected in the staged changes.")
+				return nil
+			}
+			for _, t := range trailers {
+				fmt.Println(t)
+			}
+			return nil
+		},
+	}
+}
+
+func newPrepareCommitMsgCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:    "_prepare-commit-msg <msg-file> [source] [sha]",
+		Hidden: true,
+		Args:   cobra.RangeArgs(1, 3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repoRoot, err := gitRepoRoot()
+			if err != nil {
+				return nil
+			}
+			mode := hooks.TrailerMode(repoRoot)
+			if mode == hooks.TrailersOff {
+				return nil
+			}
+			// Merges and squashes carry other commits' changes. Amends and
+			// -c/-C reuse a message whose commit holds more than what is
+			// staged, so its trailers are left as they are.
+			if len(args) > 1 && (args[1] == "merge" || args[1] == "squash" || args[1] == "commit") {
+				return nil
+			}
+
+			cfg, err := config.Load(repoRoot)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "tempo-cli: warning: %v, not adding trailers\n", err)
+				return nil
+			}
+			opts := detectOptions(cfg)
+			opts.Staged = true
+			opts.InHook = true
+			attr, err := detector.Detect(repoRoot, opts)
+			if err != nil {
+				return nil
+			}
+			trailers := detector.DisclosureTrailers(attr)
+
+			if mode == hooks.TrailersPreview {
+				for _, t := range trailers {
+					fmt.Fprintf(os.Stderr, "tempo: would add %s\n", t)
+				}
+				return nil
+			}
+			if err := hooks.AddTrailers(args[0], detector.DisclosureTrailerKey, trailers); err != nil {
+				fmt.Fprintf(os.Stderr, "tempo: adding trailers: %v\n", err)
+			}
+			return nil
+		},
+	}
+}
+
+func newSyncCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:    "_sync [remote]",
 		Hidden: true,
+		Args:   cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			repoRoot, err := gitRepoRoot()
 			if err != nil {
 				return nil
 			}
+			if _, err := config.Load(repoRoot); err != nil {
+				fmt.Fprintf(os.Stderr, "tempo-cli: warning: %v, not syncing\n", err)
+				return nil
+			}
+
+			// From the pre-push hook, hand off to a detached process so the
+			// push doesn't wait on the API
+			if foreground, _ := cmd.Flags().GetBool("foreground"); !foreground {
+				if sender.OpenStore(repoRoot).Count() == 0 && !notes.Enabled(repoRoot) {
+					return nil
+				}
+				bgArgs := []string{"_sync", "--foreground"}
+				settings, _ := cmd.Flags().GetStringArray("config")
+				for _, setting := range settings {
+					bgArgs = append(bgArgs, "--config", setting)
+				}
+				bgArgs = append(bgArgs, args...)
+				err := sender.StartBackground(repoRoot, bgArgs...)
+				if err == nil {
+					return nil
+				}
+				fmt.Fprintf(os.Stderr, "tempo-cli: warning: starting background sync: %v, syncing now\n", err)
+			}
+
+			if notes.Enabled(repoRoot) {
+				remote := "origin"
+				if len(args) > 0 {
+					remote = args[0]
+				}
+				if err := notes.Push(repoRoot, remote); err != nil {
+					fmt.Fprintf(os.Stderr, "tempo-cli: warning: pushing notes: %v\n", err)
+				}
+			}
 			return sender.Sync(repoRoot, cliVersion)
 		},
 	}
+	cmd.Flags().Bool("foreground", false, "Sync in this process and wait for it to finish")
+	return cmd
+}
+
+func newNotesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "notes",
+		Short: "Read and share attribution stored in git notes",
+	}
+
+	fetch := &cobra.Command{
+		Use:   "fetch [remote]",
+		Short: "Fetch attribution notes from a remote and merge them",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repoRoot, err := gitRepoRoot()
+			if err != nil {
+				return fmt.Errorf("not a git repository")
+			}
+			remote := "origin"
+			if len(args) > 0 {
+				remote = args[0]
+			}
+			if err := notes.Fetch(repoRoot, remote); err != nil {
+				return fmt.Errorf("fetching notes: %w", err)
+			}
+			fmt.Printf("Fetched %s from %s.\n", notes.Ref, remote)
+			return nil
+		},
+	}
+
+	show := &cobra.Command{
+		Use:   "show [commit]",
+		Short: "Show the attribution noted on a commit (default HEAD)",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repoRoot, err := gitRepoRoot()
+			if err != nil {
+				return fmt.Errorf("not a git repository")
+			}
+			rev := "HEAD"
+			if len(args) > 0 {
+				rev = args[0]
+			}
+			attr, err := notes.Read(repoRoot, rev)
+			if err == notes.ErrNoNote {
+				fmt.Printf("No attribution note on %s.\n", rev)
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+
+			jsonFlag, _ := cmd.Flags().GetBool("json")
+			if jsonFlag {
+				data, _ := json.MarshalIndent(attr, "", "  ")
+				fmt.Println(string(data))
+				return nil
+			}
+			printAttribution(attr)
+			return nil
+		},
+	}
+	show.Flags().Bool("json", false, "Output in JSON format")
+
+	cmd.AddCommand(fetch, show)
+	return cmd
+}
+
+func newPendingCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "pending",
+		Short: "Inspect queued records and triage the ones the API rejected",
+	}
+
+	show := &cobra.Command{
+		Use:   "show [record...]",
+		Short: "List pending and rejected records, or show rejected records in full",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repoRoot, err := gitRepoRoot()
+			if err != nil {
+				return fmt.Errorf("not a git repository")
+			}
+			jsonFlag, _ := cmd.Flags().GetBool("json")
+			store := sender.OpenStore(repoRoot)
+
+			if len(args) == 0 {
+				failures, err := store.Failed()
+				if err != nil {
+					return err
+				}
+				fmt.Printf("Pending: %d records waiting to sync\n", store.Count())
+				if len(failures) == 0 {
+					fmt.Println("Failed:  none")
+					return nil
+				}
+				fmt.Printf("Failed:  %d records rejected by the API\n", len(failures))
+				for _, f := range failures {
+					fmt.Printf("  %s  %-11s  %s\n", shortSHA(f.Record.CommitSHA), f.Kind, f.Error)
+				}
+				fmt.Println()
+				fmt.Println("Run 'tempo-cli pending retry <commit>' once fixed, or 'tempo-cli pending drop <commit>' to discard.")
+				return nil
+			}
+
+			failures, err := selectFailures(store, args, false)
+			if err != nil {
+				return err
+			}
+			for i, f := range failures {
+				if jsonFlag {
+					data, _ := json.MarshalIndent(f, "", "  ")
+					fmt.Println(string(data))
+					continue
+				}
+				if i > 0 {
+					fmt.Println()
+				}
+				fmt.Printf("Record:   %s (%s)\n", f.Name, f.Kind)
+				if f.Status != 0 {
+					fmt.Printf("Rejected: %s (HTTP %d)\n", f.FailedAt, f.Status)
+				} else {
+					fmt.Printf("Rejected: %s\n", f.FailedAt)
+				}
+				fmt.Printf("Error:    %s\n\n", f.Error)
+				printAttribution(f.Record)
+			}
+			return nil
+		},
+	}
+	show.Flags().Bool("json", false, "Output in JSON format")
+
+	retry := &cobra.Command{
+		Use:   "retry [record...]",
+		Short: "Queue rejected records to be sent again on the next sync",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return triageFailures(cmd, args, "Queued", (*sender.Store).Retry)
+		},
+	}
+	retry.Flags().Bool("all", false, "Retry every rejected record")
+
+	drop := &cobra.Command{
+		Use:   "drop [record...]",
+		Short: "Delete rejected records",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return triageFailures(cmd, args, "Dropped", (*sender.Store).Drop)
+		},
+	}
+	drop.Flags().Bool("all", false, "Drop every rejected record")
+
+	cmd.AddCommand(show, retry, drop)
+	return cmd
+}
+
+// triageFailures applies action to the rejected records named by args, or
+// to all of them with --all.
+func triageFailures(cmd *cobra.Command, args []string, verb string, action func(*sender.Store, *sender.Failure) error) error {
+	repoRoot, err := gitRepoRoot()
+	if err != nil {
+		return fmt.Errorf("not a git repository")
+	}
+	all, _ := cmd.Flags().GetBool("all")
+	if all == (len(args) > 0) {
+		return fmt.Errorf("name the records to %s (commit SHA, record ID or file name), or pass --all", strings.ToLower(cmd.Name()))
+	}
+	store := sender.OpenStore(repoRoot)
+	failures, err := selectFailures(store, args, all)
+	if err != nil {
+		return err
+	}
+	for _, f := range failures {
+		if err := action(store, f); err != nil {
+			return fmt.Errorf("%s: %w", f.Name, err)
+		}
+	}
+	fmt.Printf("%s %d records.\n", verb, len(failures))
+	return nil
+}
+
+// selectFailures returns the rejected records matching refs, each a commit
+// SHA or prefix of one, a record ID or a file name, or all of them.
+func selectFailures(store *sender.Store, refs []string, all bool) ([]*sender.Failure, error) {
+	failures, err := store.Failed()
+	if err != nil {
+		return nil, err
+	}
+	if all {
+		return failures, nil
+	}
+	var selected []*sender.Failure
+	for _, ref := range refs {
+		found := false
+		for _, f := range failures {
+			if f.Name == ref || f.Record.ID == ref || (len(ref) >= 4 && strings.HasPrefix(f.Record.CommitSHA, ref)) {
+				if !slices.Contains(selected, f) {
+					selected = append(selected, f)
+				}
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no rejected record matches %q", ref)
+		}
+	}
+	return selected, nil
+}
+
+func newAnnotateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "annotate <commit>",
+		Short: "Correct the AI attribution of a commit by hand",
+		Long: `Record a manual correction to a commit's attribution.
+
+  tempo-cli annotate HEAD --tool claude-code --files src/a.go,src/b.go
+  tempo-cli annotate a1b2c3d --not-ai --note "stale session"
+  tempo-cli annotate a1b2c3d --tool cursor --not-ai
+
+--tool replaces that tool's automated detections with a manual one.
+--not-ai with --tool removes that tool; alone, it marks the commit as not
+AI-assisted. Commits not yet synced are corrected in place; for synced
+commits a correction is queued for the API. A commit that was never
+recorded gets a new attribution.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repoRoot, err := gitRepoRoot()
+			if err != nil {
+				return fmt.Errorf("not a git repository")
+			}
+			sha, err := gitOutput(repoRoot, "rev-parse", "--verify", args[0]+"^{commit}")
+			if err != nil {
+				return fmt.Errorf("unknown commit %q", args[0])
+			}
+
+			tool, _ := cmd.Flags().GetString("tool")
+			files, _ := cmd.Flags().GetStringSlice("files")
+			notAI, _ := cmd.Flags().GetBool("not-ai")
+			note, _ := cmd.Flags().GetString("note")
+			who, _ := gitOutput(repoRoot, "config", "user.email")
+			c := &detector.Correction{
+				Tool:        detector.Tool(strings.ToLower(tool)),
+				Files:       files,
+				NotAI:       notAI,
+				Note:        note,
+				CorrectedBy: who,
+				CorrectedAt: time.Now().UTC().Format(time.RFC3339),
+			}
+			if err := c.Validate(); err != nil {
+				return err
+			}
+
+			cfg, err := config.Load(repoRoot)
+			if err != nil {
+				return err
+			}
+			// The files detection counts: a merge against its first
+			// parent, without the ones .tempoignore excludes
+			commitFiles, excluded, err := detector.CommittedFiles(repoRoot, sha, cfg.Ignore)
+			if err != nil {
+				return fmt.Errorf("listing files of %s: %w", args[0], err)
+			}
+			committed := make(map[string]bool)
+			for _, f := range commitFiles {
+				committed[f] = true
+			}
+			for _, f := range files {
+				switch {
+				case slices.Contains(excluded, f):
+					return fmt.Errorf("%s is excluded from attribution by %s or the ignore config key", f, detector.IgnoreFile)
+				case !committed[f]:
+					return fmt.Errorf("%s is not part of commit %s", f, args[0])
+				}
+			}
+			omitPaths := cfg.Privacy == config.PrivacyStrict
+
+			newAttribution := func() *detector.Attribution {
+				attr := &detector.Attribution{
+					CommitSHA:     sha,
+					Repo:          detector.RepoFromRemote(repoRoot),
+					Timestamp:     c.CorrectedAt,
+					FilesExcluded: len(excluded),
+					Detections:    []detector.Detection{},
+				}
+				attr.CommitAuthor, _ = gitOutput(repoRoot, "log", "-1", "--format=%ae", sha)
+				return attr
+			}
+			apply := func(attr *detector.Attribution) *detector.Attribution {
+				c.Apply(attr, len(committed))
+				if omitPaths {
+					attr.OmitPaths()
+				}
+				return attr
+			}
+
+			// A commit the API has no attribution for takes the correction
+			// as its attribution; a correction for it would be refused
+			store := sender.OpenStore(repoRoot)
+			synced, err := store.Synced(sha)
+			if err != nil {
+				return fmt.Errorf("reading synced commits: %w", err)
+			}
+			if !synced && notes.Enabled(repoRoot) {
+				// Commits synced before tempo-cli kept track still have
+				// their note
+				_, err := notes.Read(repoRoot, sha)
+				synced = err == nil
+			}
+			pending := false
+			corrected, err := store.Update(sender.KindAttribution, sha, func(attr *detector.Attribution) *detector.Attribution {
+				if attr != nil {
+					pending = true
+					return apply(attr)
+				}
+				if synced {
+					return nil
+				}
+				return apply(newAttribution())
+			})
+			if err != nil {
+				return fmt.Errorf("updating pending attribution: %w", err)
+			}
+			switch {
+			case pending:
+				fmt.Printf("Corrected pending attribution for %s.\n", shortSHA(sha))
+			case corrected != nil:
+				fmt.Printf("Recorded attribution for %s; it is sent on the next sync.\n", shortSHA(sha))
+			default:
+				// Already synced: queue a correction, on top of any still
+				// queued for the commit
+				_, err := store.Update(sender.KindCorrection, sha, func(attr *detector.Attribution) *detector.Attribution {
+					if attr == nil {
+						attr = newAttribution()
+					}
+					return apply(attr)
+				})
+				if err != nil {
+					return fmt.Errorf("saving correction: %w", err)
+				}
+				fmt.Printf("Queued correction for %s; it is sent on the next sync.\n", shortSHA(sha))
+			}
+
+			if notes.Enabled(repoRoot) {
+				noted := corrected
+				if noted == nil {
+					noted, err = notes.Read(repoRoot, sha)
+					if err == notes.ErrNoNote {
+						noted = newAttribution()
+					} else if err != nil {
+						return err
+					}
+					apply(noted)
+				}
+				if err := notes.Write(repoRoot, noted); err != nil {
+					return fmt.Errorf("writing note: %w", err)
+				}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().String("tool", "", "AI tool that wrote the commit (e.g. claude-code, cursor)")
+	cmd.Flags().StringSlice("files", nil, "Files the tool wrote (comma-separated, requires --tool)")
+	cmd.Flags().Bool("not-ai", false, "The commit, or --tool's part in it, was not AI-assisted")
+	cmd.Flags().String("note", "", "Free-text explanation kept with the correction")
+	return cmd
+}
+
+// printAttribution writes a human-readable summary of an attribution.
+func printAttribution(attr *detector.Attribution) {
+	fmt.Printf("Commit:  %s\n", attr.CommitSHA)
+	fmt.Printf("Author:  %s\n", attr.CommitAuthor)
+	if attr.Repo != "" {
+		fmt.Printf("Repo:    %s\n", attr.Repo)
+	}
+	if attr.FilesExcluded > 0 {
+		fmt.Printf("Ignored: %d committed files (%s)\n", attr.FilesExcluded, detector.IgnoreFile)
+	}
+	fmt.Println()
+
+	for _, d := range attr.Detections {
+		icon := "\U0001f7e2" // green circle
+		switch d.Confidence {
+		case detector.ConfidenceMedium:
+			icon = "\U0001f7e1" // yellow circle
+		case detector.ConfidenceLow:
+			icon = "\U0001f7e0" // orange circle
+		}
+		fmt.Printf("%s  %s (%s confidence, score %.2f, %s)\n", icon, d.Tool, d.Confidence, d.Score, d.Method)
+		if f := d.ScoreFactors; f != nil && len(f.Corroborating) > 0 {
+			fmt.Printf("   Corroborated by: %s\n", joinMethods(f.Corroborating))
+		}
+		if f := d.ScoreFactors; f != nil && f.EditsRejected > 0 {
+			fmt.Printf("   Edits: %d accepted, %d rejected\n", f.EditsAccepted, f.EditsRejected)
+		}
+
+		if len(d.FilesMatched) > 0 {
+			fmt.Printf("   Files: %d/%d committed files matched\n", d.AIFiles, d.FilesCommitted)
+			for _, f := range d.FilesMatched {
+				fmt.Printf("     - %s\n", f)
+			}
+		}
+		if d.AIFilesExcluded > 0 {
+			fmt.Printf("   Ignored: %d AI-written files\n", d.AIFilesExcluded)
+		}
+		if d.Model != "" {
+			fmt.Printf("   Model: %s\n", d.Model)
+		}
+		if d.TokenUsage > 0 {
+			fmt.Printf("   Tokens: %d\n", d.TokenUsage)
+		}
+		if d.SessionDurationSec > 0 {
+			mins := d.SessionDurationSec / 60
+			secs := d.SessionDurationSec % 60
+			fmt.Printf("   Session: %dm%ds\n", mins, secs)
+		}
+		fmt.Println()
+	}
+	if len(attr.Detections) == 0 {
+		fmt.Println("No AI tool usage.")
+		fmt.Println()
+	}
+
+	for _, c := range attr.Corrections {
+		var what string
+		switch {
+		case c.NotAI && c.Tool == "":
+			what = "not AI-assisted"
+		case c.NotAI:
+			what = fmt.Sprintf("not %s", c.Tool)
+		case c.Tool != "":
+			what = fmt.Sprintf("%s (manual)", c.Tool)
+		default:
+			what = "note"
+		}
+		fmt.Printf("Corrected: %s, by %s at %s\n", what, c.CorrectedBy, c.CorrectedAt)
+		if c.Note != "" {
+			fmt.Printf("   Note: %s\n", c.Note)
+		}
+	}
+}
+
+func joinMethods(methods []detector.Method) string {
+	names := make([]string, len(methods))
+	for i, m := range methods {
+		names[i] = string(m)
+	}
+	return strings.Join(names, ", ")
+}
+
+// detectOptions builds detector options from the CLI configuration.
+func detectOptions(cfg *config.Config) detector.Options {
+	var rules []detector.TrailerRule
+	for _, r := range cfg.TrailerRules {
+		rules = append(rules, detector.TrailerRule(r))
+	}
+	var tools []detector.Tool
+	for _, t := range cfg.Detectors {
+		tools = append(tools, detector.Tool(t))
+	}
+	return detector.Options{
+		SessionRoots:   detector.ResolveSessionRoots(cfg.SessionRoots),
+		TrailerRules:   rules,
+		IgnorePatterns: cfg.Ignore,
+		Tools:          tools,
+		SessionMaxAge:  time.Duration(cfg.SessionMaxAgeHours) * time.Hour,
+		OmitPaths:      cfg.Privacy == config.PrivacyStrict,
+	}
+}
+
+// gitOutput runs git in repoRoot and returns its trimmed output.
+func gitOutput(repoRoot string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = repoRoot
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
 }
 
 func gitRepoRoot() (string, error) {
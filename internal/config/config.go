@@ -1,60 +1,587 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 const defaultEndpoint = "https://api.usetempo.dev"
 
-// Config holds the Tempo CLI configuration stored at ~/.tempo/config.json.
+// RepoFile is the per-repo config file, committed with the code.
+const RepoFile = ".tempo.json"
+
+// Privacy modes.
+const (
+	// PrivacyStandard records the paths of AI-written files.
+	PrivacyStandard = "standard"
+	// PrivacyStrict records file counts only, never paths.
+	PrivacyStrict = "strict"
+)
+
+// Config holds the Tempo CLI configuration. It is resolved from several
+// layers, each a JSON file or source holding any subset of the keys:
+//
+//	system  /etc/tempo/config.json (TEMPO_SYSTEM_CONFIG overrides the path)
+//	user    ~/.tempo/config.json
+//	repo    .tempo.json in the repo root, committed with the code
+//	env     TEMPO_API_TOKEN, TEMPO_API_ENDPOINT, TEMPO_SESSION_MAX_AGE, TEMPO_PRIVACY
+//	flag    tempo-cli -c key=value
+//
+// Later layers override earlier ones key by key.
 type Config struct {
-	APIToken string `json:"api_token"`
-	Endpoint string `json:"endpoint"`
+	APIToken string `json:"api_token,omitempty"`
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// TokenCommand is a shell command that prints the API token, e.g.
+	// "op read op://dev/tempo/token". It takes precedence over APIToken.
+	TokenCommand string `json:"token_command,omitempty"`
+
+	// GitCredential reads the API token with git credential fill for the
+	// endpoint's host, so any configured git credential helper (OS
+	// keychain, pass, Vault) can supply it. It takes precedence over
+	// APIToken, which is then only a fallback.
+	GitCredential bool `json:"git_credential,omitempty"`
+
+	// SessionRoots overrides where AI session data is read from, keyed by
+	// tool name (e.g. "claude-code", "copilot"). Listed roots replace the
+	// built-in locations for that tool.
+	SessionRoots map[string][]string `json:"session_roots,omitempty"`
+
+	// TrailerRules add commit message trailers that attribute a commit to
+	// an AI tool. They are checked before the built-in rules.
+	TrailerRules []TrailerRule `json:"trailer_rules,omitempty"`
+
+	// Ignore lists gitignore-style patterns for paths excluded from
+	// attribution, in addition to each repo's .tempoignore.
+	Ignore []string `json:"ignore,omitempty"`
+
+	// Detectors limits detection to the listed tools (e.g. "claude-code",
+	// "cursor"). Empty enables every tool.
+	Detectors []string `json:"detectors,omitempty"`
+
+	// SessionMaxAgeHours is how old a session may be and still count.
+	// Zero uses the default of 72 hours.
+	SessionMaxAgeHours int `json:"session_max_age_hours,omitempty"`
+
+	// Privacy is PrivacyStandard (the default) or PrivacyStrict.
+	Privacy string `json:"privacy,omitempty"`
+
+	// Profiles are named accounts, each with its own token and endpoint.
+	Profiles map[string]Profile `json:"profiles,omitempty"`
+
+	// ProfileRules select a profile by the repo's origin remote. The first
+	// matching rule wins.
+	ProfileRules []ProfileRule `json:"profile_rules,omitempty"`
+
+	// Profile names the profile to use, bypassing ProfileRules.
+	Profile string `json:"profile,omitempty"`
 }
 
-func configDir() string {
-	home, _ := os.UserHomeDir()
-	return filepath.Join(home, ".tempo")
+// Profile is an account data is synced to. An empty Endpoint falls back
+// to the top-level endpoint. The token comes from TokenCommand or git
+// credential fill when set, else from APIToken.
+type Profile struct {
+	APIToken      string `json:"api_token,omitempty"`
+	Endpoint      string `json:"endpoint,omitempty"`
+	TokenCommand  string `json:"token_command,omitempty"`
+	GitCredential bool   `json:"git_credential,omitempty"`
+}
+
+// ProfileRule selects Profile for repos whose origin remote matches Match,
+// a "host/owner/repo" glob. A pattern with fewer segments matches every
+// remote below it, so "github.com/acme" and "github.com/acme/*" are the
+// same and "*" matches any remote. Profile may be OfflineProfile.
+type ProfileRule struct {
+	Match   string `json:"match"`
+	Profile string `json:"profile"`
+}
+
+// DefaultProfile is the name used for the top-level api_token and
+// endpoint when no profile applies.
+const DefaultProfile = "default"
+
+// OfflineProfile is a reserved profile name that never syncs.
+const OfflineProfile = "offline"
+
+// ResolveProfile returns the profile that applies to a repo with the given
+// origin remote ("host/owner/repo", empty if none), and its name. Without
+// a matching profile the top-level api_token and endpoint apply.
+func (c *Config) ResolveProfile(remote string) (string, Profile) {
+	name := c.Profile
+	if name == "" {
+		for _, r := range c.ProfileRules {
+			if remoteMatches(r.Match, remote) {
+				name = r.Profile
+				break
+			}
+		}
+	}
+
+	if name == OfflineProfile {
+		return name, Profile{}
+	}
+	if p, ok := c.Profiles[name]; ok {
+		if p.Endpoint == "" {
+			p.Endpoint = c.Endpoint
+		}
+		return name, p
+	}
+	if name == "" || name == DefaultProfile {
+		return DefaultProfile, Profile{
+			APIToken:      c.APIToken,
+			Endpoint:      c.Endpoint,
+			TokenCommand:  c.TokenCommand,
+			GitCredential: c.GitCredential,
+		}
+	}
+	// A rule naming a missing profile must not fall back to another account
+	return name, Profile{}
+}
+
+// remoteMatches reports whether the leading segments of remote match the
+// segments of pattern.
+func remoteMatches(pattern, remote string) bool {
+	pattern = strings.Trim(pattern, "/")
+	if pattern == "" || pattern == "*" || pattern == "**" {
+		return true
+	}
+	if remote == "" {
+		return false
+	}
+	pats := strings.Split(strings.ToLower(pattern), "/")
+	segs := strings.Split(strings.ToLower(remote), "/")
+	for i, p := range pats {
+		if p == "**" {
+			return true
+		}
+		if i >= len(segs) {
+			return false
+		}
+		if ok, _ := path.Match(p, segs[i]); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// TrailerRule maps a commit trailer to an AI tool. Pattern is a regular
+// expression matched against the trailer value; it may capture named
+// groups "model" and, when Tool is empty, "tool".
+type TrailerRule struct {
+	Key     string `json:"key"`
+	Pattern string `json:"pattern"`
+	Tool    string `json:"tool,omitempty"`
+}
+
+// sessionRootTools are the tools session_roots may name: those that keep
+// session data outside the repo.
+var sessionRootTools = []string{"claude-code", "codex", "copilot", "cursor"}
+
+// Scope names a config layer.
+type Scope string
+
+const (
+	ScopeDefault Scope = "default"
+	ScopeSystem  Scope = "system"
+	ScopeUser    Scope = "user"
+	ScopeRepo    Scope = "repo"
+	ScopeEnv     Scope = "env"
+	ScopeFlag    Scope = "flag"
+)
+
+// Origin is where a resolved config value came from: the layer and, for
+// file layers, the file (for env, the variable).
+type Origin struct {
+	Scope  Scope
+	Source string
+}
+
+func (o Origin) String() string {
+	if o.Source == "" {
+		return string(o.Scope)
+	}
+	return string(o.Scope) + ":" + o.Source
+}
+
+// repoForbidden are keys a committed repo file may not set: secrets,
+// anything that would run a command or hand out a credential on behalf of
+// whoever clones the repo, and anything that picks where the user's token
+// is sent.
+var repoForbidden = map[string]bool{
+	"api_token":      true,
+	"endpoint":       true,
+	"profiles":       true,
+	"profile":        true,
+	"profile_rules":  true,
+	"token_command":  true,
+	"git_credential": true,
+}
+
+// envKeys maps environment variables to the keys they set.
+var envKeys = []struct {
+	env string
+	key string
+}{
+	{"TEMPO_API_TOKEN", "api_token"},
+	{"TEMPO_API_ENDPOINT", "endpoint"},
+	{"TEMPO_SESSION_MAX_AGE", "session_max_age_hours"},
+	{"TEMPO_PRIVACY", "privacy"},
+}
+
+// flagOverrides are the "key=value" settings given with -c.
+var flagOverrides map[string]json.RawMessage
+
+// SetFlagOverrides sets the flag layer from "key=value" settings.
+func SetFlagOverrides(settings []string) error {
+	overrides := make(map[string]json.RawMessage)
+	for _, s := range settings {
+		key, value, ok := strings.Cut(s, "=")
+		if !ok {
+			return fmt.Errorf("invalid setting %q, want key=value", s)
+		}
+		raw, err := ParseValue(key, value)
+		if err != nil {
+			return err
+		}
+		overrides[key] = raw
+	}
+	flagOverrides = overrides
+	return nil
+}
+
+// Keys returns the known config keys, sorted.
+func Keys() []string {
+	t := reflect.TypeOf(Config{})
+	keys := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		keys = append(keys, name)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func keyField(key string) (reflect.StructField, bool) {
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == key {
+			return t.Field(i), true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// ParseValue converts a command-line value for key into JSON. Strings may
+// be given bare; lists and maps as JSON. A list of strings may also be
+// given comma-separated.
+func ParseValue(key, value string) (json.RawMessage, error) {
+	field, ok := keyField(key)
+	if !ok {
+		return nil, fmt.Errorf("unknown config key %q", key)
+	}
+	var raw json.RawMessage
+	switch {
+	case field.Type.Kind() == reflect.String:
+		raw, _ = json.Marshal(value)
+	case field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() == reflect.String && !strings.HasPrefix(strings.TrimSpace(value), "["):
+		var list []string
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				list = append(list, v)
+			}
+		}
+		raw, _ = json.Marshal(list)
+	default:
+		raw = json.RawMessage(value)
+	}
+	if err := checkValue(key, raw); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+// checkValue reports whether raw decodes into key's field.
+func checkValue(key string, raw json.RawMessage) error {
+	field, ok := keyField(key)
+	if !ok {
+		return fmt.Errorf("unknown config key %q", key)
+	}
+	v := reflect.New(field.Type)
+	if err := json.Unmarshal(raw, v.Interface()); err != nil {
+		return fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	if key == "privacy" {
+		if p := v.Elem().String(); p != "" && p != PrivacyStandard && p != PrivacyStrict {
+			return fmt.Errorf("invalid value for privacy: %q (want %q or %q)", p, PrivacyStandard, PrivacyStrict)
+		}
+	}
+	if key == "session_roots" {
+		for tool := range v.Elem().Interface().(map[string][]string) {
+			if !slices.Contains(sessionRootTools, tool) {
+				return fmt.Errorf("invalid value for session_roots: unknown tool %q (want one of %s)", tool, strings.Join(sessionRootTools, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// Layers is the resolved configuration with the origin of every value.
+type Layers struct {
+	values  map[string]json.RawMessage
+	origins map[string]Origin
 }
 
-func configPath() string {
-	return filepath.Join(configDir(), "config.json")
+// Resolve reads every config layer for the repo at repoRoot. An empty
+// repoRoot skips the repo layer. Keys a layer may not set, or sets to a
+// value of the wrong type, are skipped with a warning on stderr.
+func Resolve(repoRoot string) (*Layers, error) {
+	l := &Layers{
+		values:  map[string]json.RawMessage{"endpoint": json.RawMessage(strconv.Quote(defaultEndpoint))},
+		origins: map[string]Origin{"endpoint": {Scope: ScopeDefault}},
+	}
+
+	if err := l.mergeFile(ScopeSystem, systemConfigPath()); err != nil {
+		return nil, err
+	}
+	if err := l.mergeFile(ScopeUser, userConfigPath()); err != nil {
+		return nil, err
+	}
+	if repoRoot != "" {
+		if err := l.mergeFile(ScopeRepo, filepath.Join(repoRoot, RepoFile)); err != nil {
+			return nil, err
+		}
+	}
+	for _, e := range envKeys {
+		v := os.Getenv(e.env)
+		if v == "" {
+			continue
+		}
+		raw, err := ParseValue(e.key, v)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "tempo-cli: warning: ignoring %s: %v\n", e.env, err)
+			continue
+		}
+		l.set(e.key, raw, Origin{Scope: ScopeEnv, Source: e.env})
+	}
+	for key, raw := range flagOverrides {
+		l.set(key, raw, Origin{Scope: ScopeFlag})
+	}
+	return l, nil
+}
+
+func (l *Layers) set(key string, raw json.RawMessage, origin Origin) {
+	l.values[key] = raw
+	l.origins[key] = origin
 }
 
-// Load reads the config from ~/.tempo/config.json.
-// Returns a default config if the file does not exist.
-// The TEMPO_API_ENDPOINT env var overrides the configured endpoint.
-func Load() (*Config, error) {
-	data, err := os.ReadFile(configPath())
+func (l *Layers) mergeFile(scope Scope, path string) error {
+	values, err := readFile(path)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return &Config{Endpoint: defaultEndpoint}, nil
+		return err
+	}
+	for key, raw := range values {
+		// Older versions wrote every key, empty or not
+		if string(raw) == `""` || string(raw) == "null" {
+			continue
+		}
+		if scope == ScopeRepo && repoForbidden[key] {
+			fmt.Fprintf(os.Stderr, "tempo-cli: warning: %s may not set %s, ignoring it\n", path, key)
+			continue
+		}
+		if err := checkValue(key, raw); err != nil {
+			fmt.Fprintf(os.Stderr, "tempo-cli: warning: %s: %v\n", path, err)
+			continue
 		}
+		l.set(key, raw, Origin{Scope: scope, Source: path})
+	}
+	return nil
+}
+
+// Get returns the resolved value of key as JSON, and where it came from.
+func (l *Layers) Get(key string) (json.RawMessage, Origin, bool) {
+	raw, ok := l.values[key]
+	return raw, l.origins[key], ok
+}
+
+// Defined returns the keys that have a value, sorted.
+func (l *Layers) Defined() []string {
+	keys := make([]string, 0, len(l.values))
+	for key := range l.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Config decodes the resolved values.
+func (l *Layers) Config() (*Config, error) {
+	data, err := json.Marshal(l.values)
+	if err != nil {
 		return nil, err
 	}
 	var cfg Config
 	if err := json.Unmarshal(data, &cfg); err != nil {
 		return nil, err
 	}
+	// Files written by older versions always carry an endpoint, often empty
 	if cfg.Endpoint == "" {
 		cfg.Endpoint = defaultEndpoint
 	}
-	if ep := os.Getenv("TEMPO_API_ENDPOINT"); ep != "" {
-		cfg.Endpoint = ep
-	}
 	return &cfg, nil
 }
 
-// Save writes the config to ~/.tempo/config.json with mode 0600.
-func Save(cfg *Config) error {
-	if err := os.MkdirAll(configDir(), 0700); err != nil {
+// Load resolves the config for the repo at repoRoot (empty for none).
+// A missing file contributes nothing.
+func Load(repoRoot string) (*Config, error) {
+	l, err := Resolve(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	return l.Config()
+}
+
+// SetValue writes key to the config file of scope (ScopeUser, ScopeRepo
+// or ScopeSystem), leaving its other keys alone. A nil raw removes the key.
+func SetValue(scope Scope, repoRoot, key string, raw json.RawMessage) error {
+	if raw != nil {
+		if err := checkValue(key, raw); err != nil {
+			return err
+		}
+	}
+	var path string
+	mode := os.FileMode(0644)
+	switch scope {
+	case ScopeUser:
+		path = userConfigPath()
+		mode = 0600
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return err
+		}
+	case ScopeRepo:
+		if repoForbidden[key] {
+			return fmt.Errorf("%s may not be set in %s, which is committed", key, RepoFile)
+		}
+		path = filepath.Join(repoRoot, RepoFile)
+	case ScopeSystem:
+		path = systemConfigPath()
+	default:
+		return fmt.Errorf("cannot write %s config", scope)
+	}
+
+	values, err := readFile(path)
+	if err != nil {
+		return err
+	}
+	if values == nil {
+		values = make(map[string]json.RawMessage)
+	}
+	if raw == nil {
+		delete(values, key)
+	} else {
+		values[key] = raw
+	}
+
+	data, err := json.MarshalIndent(values, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), mode)
+}
+
+// SetProfile updates the named profile in the user config. Empty fields
+// of p leave the stored ones unchanged.
+func SetProfile(name string, p Profile) error {
+	profiles, err := readUserProfiles()
+	if err != nil {
 		return err
 	}
-	data, err := json.MarshalIndent(cfg, "", "  ")
+	stored := profiles[name]
+	if p.APIToken != "" {
+		stored.APIToken = p.APIToken
+	}
+	if p.Endpoint != "" {
+		stored.Endpoint = p.Endpoint
+	}
+	if p.TokenCommand != "" {
+		stored.TokenCommand = p.TokenCommand
+	}
+	if p.GitCredential {
+		stored.GitCredential = true
+	}
+	profiles[name] = stored
+	return writeUserProfiles(profiles)
+}
+
+// readUserProfiles returns the profiles defined in the user config.
+func readUserProfiles() (map[string]Profile, error) {
+	values, err := readFile(userConfigPath())
+	if err != nil {
+		return nil, err
+	}
+	profiles := make(map[string]Profile)
+	if raw, ok := values["profiles"]; ok {
+		if err := json.Unmarshal(raw, &profiles); err != nil {
+			return nil, fmt.Errorf("%s: profiles: %w", userConfigPath(), err)
+		}
+	}
+	return profiles, nil
+}
+
+// writeUserProfiles replaces the profiles in the user config, removing
+// the key when none are left.
+func writeUserProfiles(profiles map[string]Profile) error {
+	if len(profiles) == 0 {
+		return SetValue(ScopeUser, "", "profiles", nil)
+	}
+	raw, err := json.Marshal(profiles)
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(configPath(), data, 0600)
+	return SetValue(ScopeUser, "", "profiles", raw)
+}
+
+// readFile reads a config file as raw values by key. A missing file
+// yields no values.
+func readFile(path string) (map[string]json.RawMessage, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, nil
+	}
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return values, nil
+}
+
+func userConfigPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".tempo", "config.json")
+}
+
+func systemConfigPath() string {
+	if p := os.Getenv("TEMPO_SYSTEM_CONFIG"); p != "" {
+		return p
+	}
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("ProgramData"), "tempo", "config.json")
+	}
+	return "/etc/tempo/config.json"
 }
@@ -0,0 +1,246 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeJSON(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// setupLayers points every file layer at a temp dir and returns the
+// repo root.
+func setupLayers(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("TEMPO_SYSTEM_CONFIG", filepath.Join(home, "system.json"))
+	for _, e := range envKeys {
+		t.Setenv(e.env, "")
+	}
+	t.Cleanup(func() { flagOverrides = nil })
+	return t.TempDir()
+}
+
+func TestResolve_Layers(t *testing.T) {
+	repoRoot := setupLayers(t)
+	home := os.Getenv("HOME")
+	writeJSON(t, os.Getenv("TEMPO_SYSTEM_CONFIG"), `{"privacy": "strict", "session_max_age_hours": 12}`)
+	writeJSON(t, filepath.Join(home, ".tempo", "config.json"), `{"api_token": "tpo_user", "endpoint": "", "session_max_age_hours": 24}`)
+	writeJSON(t, filepath.Join(repoRoot, RepoFile), `{"api_token": "tpo_repo", "detectors": ["claude-code"], "ignore": ["vendor/"]}`)
+	t.Setenv("TEMPO_API_ENDPOINT", "https://tempo.example.com")
+	if err := SetFlagOverrides([]string{"privacy=standard"}); err != nil {
+		t.Fatal(err)
+	}
+
+	layers, err := Resolve(repoRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := layers.Config()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.APIToken != "tpo_user" {
+		t.Errorf("api_token: got %q, want the user token (repo may not set it)", cfg.APIToken)
+	}
+	if cfg.Endpoint != "https://tempo.example.com" {
+		t.Errorf("endpoint: got %q", cfg.Endpoint)
+	}
+	if cfg.SessionMaxAgeHours != 24 {
+		t.Errorf("session_max_age_hours: got %d, want 24", cfg.SessionMaxAgeHours)
+	}
+	if cfg.Privacy != PrivacyStandard {
+		t.Errorf("privacy: got %q, want the flag value", cfg.Privacy)
+	}
+	if len(cfg.Detectors) != 1 || cfg.Detectors[0] != "claude-code" {
+		t.Errorf("detectors: got %v", cfg.Detectors)
+	}
+
+	origins := map[string]Scope{
+		"api_token":             ScopeUser,
+		"endpoint":              ScopeEnv,
+		"session_max_age_hours": ScopeUser,
+		"privacy":               ScopeFlag,
+		"detectors":             ScopeRepo,
+		"ignore":                ScopeRepo,
+	}
+	for key, want := range origins {
+		if _, origin, ok := layers.Get(key); !ok || origin.Scope != want {
+			t.Errorf("%s: got origin %v, want %s", key, origin, want)
+		}
+	}
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	setupLayers(t)
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Endpoint != defaultEndpoint || cfg.APIToken != "" {
+		t.Errorf("got %+v, want defaults", cfg)
+	}
+}
+
+func TestResolve_InvalidValueSkipped(t *testing.T) {
+	repoRoot := setupLayers(t)
+	writeJSON(t, filepath.Join(repoRoot, RepoFile), `{"session_max_age_hours": "soon", "privacy": "loose", "session_roots": {"claude": ["/tmp"]}, "ignore": ["*.lock"]}`)
+	cfg, err := Load(repoRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.SessionMaxAgeHours != 0 || cfg.Privacy != "" || cfg.SessionRoots != nil {
+		t.Errorf("invalid values should be skipped, got %+v", cfg)
+	}
+	if len(cfg.Ignore) != 1 {
+		t.Errorf("valid values should be kept, got %v", cfg.Ignore)
+	}
+}
+
+func TestSetValue(t *testing.T) {
+	repoRoot := setupLayers(t)
+	path := filepath.Join(repoRoot, RepoFile)
+	writeJSON(t, path, `{"ignore": ["vendor/"]}`)
+
+	raw, err := ParseValue("detectors", "claude-code, cursor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValue(ScopeRepo, repoRoot, "detectors", raw); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValue(ScopeRepo, repoRoot, "api_token", json.RawMessage(`"tpo_x"`)); err == nil {
+		t.Error("api_token should not be writable to the repo file")
+	}
+
+	cfg, _ := Load(repoRoot)
+	if len(cfg.Ignore) != 1 || len(cfg.Detectors) != 2 || cfg.Detectors[1] != "cursor" {
+		t.Errorf("got %+v", cfg)
+	}
+
+	if err := SetValue(ScopeRepo, repoRoot, "ignore", nil); err != nil {
+		t.Fatal(err)
+	}
+	cfg, _ = Load(repoRoot)
+	if len(cfg.Ignore) != 0 {
+		t.Errorf("ignore should be removed, got %v", cfg.Ignore)
+	}
+
+	// The user file holds the token, so it is private
+	if err := SetValue(ScopeUser, "", "api_token", json.RawMessage(`"tpo_x"`)); err != nil {
+		t.Fatal(err)
+	}
+	st, err := os.Stat(userConfigPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Mode().Perm() != 0600 {
+		t.Errorf("user config mode: got %v, want 0600", st.Mode().Perm())
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		key, value string
+		want       string
+		wantErr    bool
+	}{
+		{"endpoint", "https://x", `"https://x"`, false},
+		{"ignore", "a, b", `["a","b"]`, false},
+		{"ignore", `["a"]`, `["a"]`, false},
+		{"session_max_age_hours", "48", `48`, false},
+		{"session_max_age_hours", "two days", "", true},
+		{"privacy", "strict", `"strict"`, false},
+		{"privacy", "loose", "", true},
+		{"session_roots", `{"cursor": ["~/cursor"]}`, `{"cursor": ["~/cursor"]}`, false},
+		{"session_roots", `{"claude": ["~/.claude/projects"]}`, "", true},
+		{"nope", "x", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseValue(tt.key, tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseValue(%q, %q): err %v, wantErr %v", tt.key, tt.value, err, tt.wantErr)
+			continue
+		}
+		if err == nil && string(got) != tt.want {
+			t.Errorf("ParseValue(%q, %q): got %s, want %s", tt.key, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestResolveProfile(t *testing.T) {
+	cfg := &Config{
+		APIToken: "tpo_default",
+		Endpoint: defaultEndpoint,
+		Profiles: map[string]Profile{
+			"acme":   {APIToken: "tpo_acme", Endpoint: "https://tempo.acme.dev"},
+			"globex": {APIToken: "tpo_globex"},
+		},
+		ProfileRules: []ProfileRule{
+			{Match: "github.com/acme/*", Profile: "acme"},
+			{Match: "gitlab.globex.com", Profile: "globex"},
+			{Match: "github.com/initech", Profile: "initech"},
+			{Match: "*", Profile: OfflineProfile},
+		},
+	}
+
+	tests := []struct {
+		remote    string
+		wantName  string
+		wantToken string
+		wantEP    string
+	}{
+		{"github.com/acme/api", "acme", "tpo_acme", "https://tempo.acme.dev"},
+		{"github.com/ACME/web", "acme", "tpo_acme", "https://tempo.acme.dev"},
+		{"gitlab.globex.com/platform/infra/api", "globex", "tpo_globex", defaultEndpoint},
+		{"github.com/initech/tps", "initech", "", ""},
+		{"github.com/torvalds/linux", OfflineProfile, "", ""},
+		{"", OfflineProfile, "", ""},
+	}
+	for _, tt := range tests {
+		name, p := cfg.ResolveProfile(tt.remote)
+		if name != tt.wantName || p.APIToken != tt.wantToken || p.Endpoint != tt.wantEP {
+			t.Errorf("ResolveProfile(%q): got %s %+v, want %s {%s %s}", tt.remote, name, p, tt.wantName, tt.wantToken, tt.wantEP)
+		}
+	}
+
+	// An explicit profile bypasses the rules
+	cfg.Profile = "globex"
+	if name, _ := cfg.ResolveProfile("github.com/acme/api"); name != "globex" {
+		t.Errorf("explicit profile: got %s, want globex", name)
+	}
+
+	// Without rules the top-level token applies
+	cfg = &Config{APIToken: "tpo_default", Endpoint: defaultEndpoint}
+	if name, p := cfg.ResolveProfile("github.com/acme/api"); name != DefaultProfile || p.APIToken != "tpo_default" {
+		t.Errorf("default: got %s %+v", name, p)
+	}
+}
+
+func TestSetProfile(t *testing.T) {
+	setupLayers(t)
+	if err := SetProfile("acme", Profile{APIToken: "tpo_1", Endpoint: "https://tempo.acme.dev"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetProfile("acme", Profile{APIToken: "tpo_2"}); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := cfg.Profiles["acme"]; p.APIToken != "tpo_2" || p.Endpoint != "https://tempo.acme.dev" {
+		t.Errorf("got %+v", p)
+	}
+}
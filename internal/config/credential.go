@@ -0,0 +1,280 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// credentialUser is the username Tempo tokens are stored under with git
+// credential helpers, which match on protocol, host and username.
+const credentialUser = "tempo"
+
+// TokenSource describes where the profile's token comes from, without
+// fetching it: "token_command", "git credential", "config file", or "" if
+// the profile has none.
+func (p Profile) TokenSource() string {
+	switch {
+	case p.TokenCommand != "":
+		return "token_command"
+	case p.GitCredential:
+		return "git credential"
+	case p.APIToken != "":
+		return "config file"
+	}
+	return ""
+}
+
+// Token returns the profile's API token: the output of TokenCommand, else
+// the password git credential fill returns for the endpoint, else the
+// plaintext APIToken. A source that fails is skipped; its error is
+// returned only if no later source has a token. An empty token with a nil
+// error means offline.
+func (p Profile) Token() (string, error) {
+	var firstErr error
+	if p.TokenCommand != "" {
+		token, err := runTokenCommand(p.TokenCommand)
+		if token != "" {
+			return token, nil
+		}
+		firstErr = err
+	}
+	if p.GitCredential {
+		token, err := gitCredentialFill(p.Endpoint)
+		if token != "" {
+			return token, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if p.APIToken != "" {
+		return p.APIToken, nil
+	}
+	return "", firstErr
+}
+
+func runTokenCommand(command string) (string, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("token_command: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// credentialRequest is the git credential description of endpoint.
+func credentialRequest(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("invalid endpoint %q", endpoint)
+	}
+	return fmt.Sprintf("protocol=%s\nhost=%s\nusername=%s\n", u.Scheme, u.Host, credentialUser), nil
+}
+
+// gitCredentialFill asks git's credential helpers for the endpoint's
+// token. It never prompts, since it may run from a hook.
+func gitCredentialFill(endpoint string) (string, error) {
+	req, err := credentialRequest(endpoint)
+	if err != nil {
+		return "", err
+	}
+	cmd := exec.Command("git", "credential", "fill")
+	cmd.Stdin = strings.NewReader(req + "\n")
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_ASKPASS=", "SSH_ASKPASS=")
+	out, err := cmd.Output()
+	if err != nil {
+		// No helper has it and prompting is off
+		return "", nil
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		if v, ok := strings.CutPrefix(line, "password="); ok {
+			return strings.TrimSpace(v), nil
+		}
+	}
+	return "", nil
+}
+
+// StoreGitCredential saves token with git credential approve, so the
+// configured helper keeps it instead of the plaintext config file.
+func StoreGitCredential(endpoint, token string) error {
+	return gitCredential("approve", endpoint, token)
+}
+
+// EraseGitCredential removes the endpoint's token from the credential
+// helpers.
+func EraseGitCredential(endpoint string) error {
+	return gitCredential("reject", endpoint, "")
+}
+
+func gitCredential(action, endpoint, token string) error {
+	req, err := credentialRequest(endpoint)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req += "password=" + token + "\n"
+	}
+	cmd := exec.Command("git", "credential", action)
+	cmd.Stdin = strings.NewReader(req + "\n")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git credential %s: %w: %s", action, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// SaveToken stores token for the named profile, or for the top-level
+// account when name is "" or DefaultProfile, in the user config. endpoint,
+// if set, is saved with it. With gitCredential, or when the account already
+// reads its token with git credential, the token goes to git credential
+// approve and the config file only records git_credential. Returns where
+// the token was stored.
+func SaveToken(name, endpoint, token string, gitCredential bool) (string, error) {
+	cfg, err := Load("")
+	if err != nil {
+		return "", err
+	}
+	if name == DefaultProfile {
+		name = ""
+	}
+	current := Profile{APIToken: cfg.APIToken, Endpoint: cfg.Endpoint, GitCredential: cfg.GitCredential}
+	if name != "" {
+		current = cfg.Profiles[name]
+		if current.Endpoint == "" {
+			current.Endpoint = cfg.Endpoint
+		}
+	}
+	credEndpoint := endpoint
+	if credEndpoint == "" {
+		credEndpoint = current.Endpoint
+	}
+
+	if gitCredential || current.GitCredential {
+		if err := StoreGitCredential(credEndpoint, token); err != nil {
+			return "", err
+		}
+		// Drop any plaintext copy now that a helper holds the token
+		token = ""
+		gitCredential = true
+	}
+
+	if name != "" {
+		p := Profile{APIToken: token, Endpoint: endpoint, GitCredential: gitCredential}
+		if err := SetProfile(name, p); err != nil {
+			return "", err
+		}
+		if token == "" {
+			if err := clearProfileToken(name); err != nil {
+				return "", err
+			}
+		}
+	} else {
+		var tokenRaw json.RawMessage
+		if token != "" {
+			tokenRaw, _ = json.Marshal(token)
+		}
+		if err := SetValue(ScopeUser, "", "api_token", tokenRaw); err != nil {
+			return "", err
+		}
+		if endpoint != "" {
+			endpointRaw, _ := json.Marshal(endpoint)
+			if err := SetValue(ScopeUser, "", "endpoint", endpointRaw); err != nil {
+				return "", err
+			}
+		}
+		if gitCredential {
+			if err := SetValue(ScopeUser, "", "git_credential", json.RawMessage("true")); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	if gitCredential {
+		return "git credential helper", nil
+	}
+	return userConfigPath(), nil
+}
+
+// RemoveToken deletes the stored token of the named profile, or of the
+// top-level account when name is "" or DefaultProfile: the plaintext copy
+// in the user config and, if the account reads it with git credential,
+// the helper's copy. With removeProfile a named profile is deleted
+// altogether. A token_command is left alone, since Tempo didn't write it.
+func RemoveToken(name string, removeProfile bool) error {
+	cfg, err := Load("")
+	if err != nil {
+		return err
+	}
+	if name == DefaultProfile {
+		name = ""
+	}
+
+	if name == "" {
+		if cfg.GitCredential {
+			if err := EraseGitCredential(cfg.Endpoint); err != nil {
+				return err
+			}
+		}
+		for _, key := range []string{"api_token", "git_credential"} {
+			if err := SetValue(ScopeUser, "", key, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	profiles, err := readUserProfiles()
+	if err != nil {
+		return err
+	}
+	p, ok := profiles[name]
+	if !ok {
+		return fmt.Errorf("no profile %q in %s", name, userConfigPath())
+	}
+	if p.GitCredential {
+		endpoint := p.Endpoint
+		if endpoint == "" {
+			endpoint = cfg.Endpoint
+		}
+		if err := EraseGitCredential(endpoint); err != nil {
+			return err
+		}
+	}
+	if removeProfile {
+		delete(profiles, name)
+	} else {
+		p.APIToken = ""
+		p.GitCredential = false
+		profiles[name] = p
+	}
+	return writeUserProfiles(profiles)
+}
+
+// clearProfileToken removes the plaintext token of a profile in the user
+// config.
+func clearProfileToken(name string) error {
+	profiles, err := readUserProfiles()
+	if err != nil {
+		return err
+	}
+	p, ok := profiles[name]
+	if !ok || p.APIToken == "" {
+		return nil
+	}
+	p.APIToken = ""
+	profiles[name] = p
+	return writeUserProfiles(profiles)
+}
@@ -0,0 +1,193 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestProfileToken_Command(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+
+	p := Profile{APIToken: "tpo_plain", TokenCommand: "echo tpo_cmd"}
+	if got, err := p.Token(); err != nil || got != "tpo_cmd" {
+		t.Errorf("got %q, %v; want the command's token", got, err)
+	}
+	if p.TokenSource() != "token_command" {
+		t.Errorf("source: got %q", p.TokenSource())
+	}
+
+	// A failing command falls back to the plaintext token
+	p.TokenCommand = "exit 1"
+	if got, err := p.Token(); err != nil || got != "tpo_plain" {
+		t.Errorf("got %q, %v; want the plaintext fallback", got, err)
+	}
+
+	// With nothing to fall back to the error is reported
+	p.APIToken = ""
+	if _, err := p.Token(); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+// setupGitCredentialStore points git at a credential store file in a temp
+// dir, isolated from the user's git config.
+func setupGitCredentialStore(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	store := filepath.Join(dir, "credentials")
+	gitconfig := filepath.Join(dir, "gitconfig")
+	content := "[credential]\n\thelper = store --file " + filepath.ToSlash(store) + "\n"
+	if err := os.WriteFile(gitconfig, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", gitconfig)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	return store
+}
+
+func TestGitCredential(t *testing.T) {
+	store := setupGitCredentialStore(t)
+	endpoint := "https://api.tempo.example.com"
+
+	p := Profile{Endpoint: endpoint, GitCredential: true}
+	if got, err := p.Token(); err != nil || got != "" {
+		t.Errorf("before storing: got %q, %v; want no token", got, err)
+	}
+
+	if err := StoreGitCredential(endpoint, "tpo_secret"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := p.Token(); err != nil || got != "tpo_secret" {
+		t.Errorf("got %q, %v; want the stored token", got, err)
+	}
+	data, _ := os.ReadFile(store)
+	if !strings.Contains(string(data), "api.tempo.example.com") {
+		t.Errorf("credential store: got %q", data)
+	}
+
+	if err := EraseGitCredential(endpoint); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := p.Token(); got != "" {
+		t.Errorf("after erasing: got %q", got)
+	}
+}
+
+func TestSaveToken_GitCredential(t *testing.T) {
+	setupLayers(t)
+	setupGitCredentialStore(t)
+
+	// A plaintext token saved earlier is replaced by the helper
+	if _, err := SaveToken("", "", "tpo_old", false); err != nil {
+		t.Fatal(err)
+	}
+	where, err := SaveToken("", "https://api.tempo.example.com", "tpo_new", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if where != "git credential helper" {
+		t.Errorf("where: got %q", where)
+	}
+
+	data, _ := os.ReadFile(userConfigPath())
+	if strings.Contains(string(data), "tpo_") {
+		t.Errorf("config file still holds a token: %s", data)
+	}
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	name, p := cfg.ResolveProfile("")
+	if name != DefaultProfile || p.TokenSource() != "git credential" {
+		t.Errorf("got %s %+v", name, p)
+	}
+	if token, err := p.Token(); err != nil || token != "tpo_new" {
+		t.Errorf("token: got %q, %v", token, err)
+	}
+}
+
+func TestResolve_RepoMayNotRunCommands(t *testing.T) {
+	repoRoot := setupLayers(t)
+	writeJSON(t, filepath.Join(repoRoot, RepoFile), `{"token_command": "curl evil.example", "git_credential": true}`)
+	cfg, err := Load(repoRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.TokenCommand != "" || cfg.GitCredential {
+		t.Errorf("repo file set credential keys: %+v", cfg)
+	}
+}
+
+func TestResolve_RepoMayNotRedirectToken(t *testing.T) {
+	repoRoot := setupLayers(t)
+	writeJSON(t, userConfigPath(), `{"api_token": "tpo_user", "profiles": {"acme": {"api_token": "tpo_acme"}}}`)
+	writeJSON(t, filepath.Join(repoRoot, RepoFile), `{"endpoint": "https://evil.example", "profile": "acme", "profile_rules": [{"match": "*", "profile": "acme"}]}`)
+	cfg, err := Load(repoRoot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name, p := cfg.ResolveProfile("github.com/acme/app")
+	if name != DefaultProfile || p.Endpoint != defaultEndpoint || p.APIToken != "tpo_user" {
+		t.Errorf("got %s %+v, want the user's token for the default endpoint", name, p)
+	}
+	if err := SetValue(ScopeRepo, repoRoot, "endpoint", json.RawMessage(`"https://evil.example"`)); err == nil {
+		t.Error("setting endpoint in the repo file should fail")
+	}
+}
+
+func TestRemoveToken(t *testing.T) {
+	setupLayers(t)
+	store := setupGitCredentialStore(t)
+
+	if _, err := SaveToken("", "", "tpo_default", false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SaveToken("acme", "https://tempo.acme.dev", "tpo_acme", true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SaveToken("globex", "", "tpo_globex", false); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RemoveToken(DefaultProfile, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := RemoveToken("acme", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := RemoveToken("globex", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := RemoveToken("initech", false); err == nil {
+		t.Error("removing a missing profile should fail")
+	}
+
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.APIToken != "" {
+		t.Errorf("default token not removed: %q", cfg.APIToken)
+	}
+	acme, ok := cfg.Profiles["acme"]
+	if !ok || acme.TokenSource() != "" || acme.Endpoint != "https://tempo.acme.dev" {
+		t.Errorf("acme: got %+v, %v; want the profile kept without a token", acme, ok)
+	}
+	if _, ok := cfg.Profiles["globex"]; ok {
+		t.Error("globex profile not removed")
+	}
+	if data, _ := os.ReadFile(store); strings.Contains(string(data), "tpo_acme") {
+		t.Errorf("credential helper still holds the token: %s", data)
+	}
+}
@@ -0,0 +1,191 @@
+package detector
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Agent-commit detection.
+//
+// When an agent runs git commit itself, the post-commit hook inherits the
+// agent's shell: its environment and its process ancestry
+// (hook → git → shell → agent). Either identifies the committing agent.
+//
+// TERM_PROGRAM is not used: editors set it for every integrated terminal,
+// so it is the same whether a person or an agent typed the command. For the
+// same reason editor processes (Cursor, VS Code) in the ancestry don't
+// count; only their agent-specific variables do.
+
+// agentEnvVars are environment variables agents set in the shells they
+// run commands in. An empty value matches any non-empty setting.
+var agentEnvVars = []struct {
+	name  string
+	value string
+	tool  Tool
+}{
+	{"CLAUDECODE", "1", ToolClaudeCode},
+	{"CODEX_SANDBOX", "", ToolCodex},
+	{"CODEX_SANDBOX_NETWORK_DISABLED", "", ToolCodex},
+	{"CURSOR_AGENT", "1", ToolCursor},
+}
+
+// agentProcessTools are the tools whose processes only run commands on
+// the agent's behalf, so finding one among our ancestors is conclusive.
+var agentProcessTools = map[Tool]bool{
+	ToolClaudeCode: true,
+	ToolCodex:      true,
+	ToolAider:      true,
+}
+
+// maxAncestors bounds the parent-chain walk.
+const maxAncestors = 32
+
+// processEntry is one process in the parent chain.
+type processEntry struct {
+	comm string
+	args []string
+}
+
+// detectAgentCommit reports which agent, if any, ran the git commit that
+// invoked this hook.
+func detectAgentCommit() (Tool, bool) {
+	if tool, ok := agentFromEnv(os.Getenv); ok {
+		return tool, true
+	}
+	return agentFromAncestors(processAncestors(os.Getppid()))
+}
+
+// markAgentCommit upgrades the session detection of tool to an agent
+// commit. Other detections of the tool keep their method. It reports
+// whether the tool was detected at all.
+func markAgentCommit(detections []Detection, tool Tool) bool {
+	detected := false
+	for i := range detections {
+		d := &detections[i]
+		if d.Tool != tool {
+			continue
+		}
+		detected = true
+		if d.Method == MethodFileMatch {
+			d.Method = MethodAgentCommit
+			return true
+		}
+	}
+	return detected
+}
+
+// agentFromEnv checks the environment for variables set by agent shells.
+func agentFromEnv(getenv func(string) string) (Tool, bool) {
+	for _, v := range agentEnvVars {
+		val := getenv(v.name)
+		if val == "" {
+			continue
+		}
+		if v.value == "" || val == v.value {
+			return v.tool, true
+		}
+	}
+	return "", false
+}
+
+// agentFromAncestors returns the nearest agent in the parent chain.
+func agentFromAncestors(ancestors []processEntry) (Tool, bool) {
+	for _, p := range ancestors {
+		if tool, ok := processTool(p.comm, p.args); ok && agentProcessTools[tool] {
+			return tool, true
+		}
+	}
+	return "", false
+}
+
+// processAncestors returns pid and its ancestors, nearest first.
+func processAncestors(pid int) []processEntry {
+	switch runtime.GOOS {
+	case "linux":
+		return procAncestors("/proc", pid)
+	case "darwin":
+		return psAncestors(pid)
+	}
+	return nil
+}
+
+// procAncestors walks the parent chain through a /proc-style tree.
+func procAncestors(procRoot string, pid int) []processEntry {
+	var chain []processEntry
+	for i := 0; i < maxAncestors && pid > 1; i++ {
+		dir := filepath.Join(procRoot, strconv.Itoa(pid))
+		stat, err := os.ReadFile(filepath.Join(dir, "stat"))
+		if err != nil {
+			break
+		}
+		comm, _ := os.ReadFile(filepath.Join(dir, "comm"))
+		cmdline, _ := os.ReadFile(filepath.Join(dir, "cmdline"))
+		chain = append(chain, processEntry{
+			comm: strings.TrimSpace(string(comm)),
+			args: splitCmdline(cmdline),
+		})
+		pid = statPPID(string(stat))
+	}
+	return chain
+}
+
+// statPPID extracts the parent PID from /proc/<pid>/stat. The comm field
+// is parenthesized and may contain spaces, so fields are counted from the
+// last ')'.
+func statPPID(stat string) int {
+	i := strings.LastIndexByte(stat, ')')
+	if i < 0 {
+		return 0
+	}
+	fields := strings.Fields(stat[i+1:])
+	if len(fields) < 2 {
+		return 0
+	}
+	ppid, _ := strconv.Atoi(fields[1])
+	return ppid
+}
+
+// psAncestors walks the parent chain using a single ps listing.
+func psAncestors(pid int) []processEntry {
+	out, err := exec.Command("ps", "-A", "-o", "pid=", "-o", "ppid=", "-o", "args=").Output()
+	if err != nil {
+		return nil
+	}
+	return parsePSAncestors(string(out), pid)
+}
+
+// parsePSAncestors builds the parent chain of pid from "pid ppid args" lines.
+func parsePSAncestors(out string, pid int) []processEntry {
+	type psEntry struct {
+		ppid int
+		args []string
+	}
+	table := make(map[int]psEntry)
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		p, err1 := strconv.Atoi(fields[0])
+		ppid, err2 := strconv.Atoi(fields[1])
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		table[p] = psEntry{ppid: ppid, args: fields[2:]}
+	}
+
+	var chain []processEntry
+	for i := 0; i < maxAncestors && pid > 1; i++ {
+		e, ok := table[pid]
+		if !ok {
+			break
+		}
+		chain = append(chain, processEntry{comm: filepath.Base(e.args[0]), args: e.args})
+		pid = e.ppid
+	}
+	return chain
+}
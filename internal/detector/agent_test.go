@@ -0,0 +1,131 @@
+package detector
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAgentFromEnv(t *testing.T) {
+	tests := []struct {
+		name   string
+		env    map[string]string
+		want   Tool
+		wantOK bool
+	}{
+		{"claude code", map[string]string{"CLAUDECODE": "1"}, ToolClaudeCode, true},
+		{"codex sandbox", map[string]string{"CODEX_SANDBOX": "seatbelt"}, ToolCodex, true},
+		{"codex network disabled", map[string]string{"CODEX_SANDBOX_NETWORK_DISABLED": "1"}, ToolCodex, true},
+		{"cursor agent", map[string]string{"CURSOR_AGENT": "1"}, ToolCursor, true},
+		{"editor terminal only", map[string]string{"TERM_PROGRAM": "vscode"}, "", false},
+		{"claudecode disabled", map[string]string{"CLAUDECODE": "0"}, "", false},
+		{"empty", nil, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := agentFromEnv(func(k string) string { return tt.env[k] })
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("got %q, %v; want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+// writeFakeProcStat adds a stat file recording ppid to a fake /proc entry.
+func writeFakeProcStat(t *testing.T, procRoot string, pid, ppid int, comm string, argv []string) {
+	t.Helper()
+	writeFakeProc(t, procRoot, fmt.Sprint(pid), comm, argv, "")
+	stat := fmt.Sprintf("%d (%s) S %d 1 1 0 -1", pid, comm, ppid)
+	if err := os.WriteFile(filepath.Join(procRoot, fmt.Sprint(pid), "stat"), []byte(stat), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProcAncestors(t *testing.T) {
+	procRoot := t.TempDir()
+	// hook sh → git → bash → claude → zsh
+	writeFakeProcStat(t, procRoot, 50, 1, "zsh", []string{"-zsh"})
+	writeFakeProcStat(t, procRoot, 60, 50, "claude", []string{"claude"})
+	writeFakeProcStat(t, procRoot, 70, 60, "bash", []string{"/bin/bash", "-c", "git commit -m x"})
+	writeFakeProcStat(t, procRoot, 80, 70, "git", []string{"git", "commit", "-m", "x"})
+	writeFakeProcStat(t, procRoot, 90, 80, "sh", []string{"/bin/sh", ".git/hooks/post-commit"})
+
+	chain := procAncestors(procRoot, 90)
+	if len(chain) != 5 {
+		t.Fatalf("got %d ancestors, want 5: %+v", len(chain), chain)
+	}
+	if chain[0].comm != "sh" || chain[3].comm != "claude" {
+		t.Errorf("chain: got %+v", chain)
+	}
+
+	tool, ok := agentFromAncestors(chain)
+	if !ok || tool != ToolClaudeCode {
+		t.Errorf("got %q, %v; want claude-code", tool, ok)
+	}
+}
+
+func TestAgentFromAncestors_EditorTerminal(t *testing.T) {
+	// A person committing from Cursor's integrated terminal
+	chain := []processEntry{
+		{comm: "sh", args: []string{"/bin/sh", ".git/hooks/post-commit"}},
+		{comm: "git", args: []string{"git", "commit"}},
+		{comm: "zsh", args: []string{"/bin/zsh"}},
+		{comm: "cursor", args: []string{"/usr/share/cursor/cursor", "--type=ptyHost"}},
+	}
+	if tool, ok := agentFromAncestors(chain); ok {
+		t.Errorf("expected no agent, got %q", tool)
+	}
+}
+
+func TestStatPPID(t *testing.T) {
+	if got := statPPID("1234 (my (weird) proc) S 42 1234 1234 0"); got != 42 {
+		t.Errorf("got %d, want 42", got)
+	}
+	if got := statPPID("garbage"); got != 0 {
+		t.Errorf("got %d, want 0", got)
+	}
+}
+
+func TestParsePSAncestors(t *testing.T) {
+	out := `    1     0 /sbin/launchd
+  500     1 /usr/local/bin/node /usr/local/bin/codex
+  600   500 /bin/zsh -c git commit -m x
+  700   600 git commit -m x
+  800   700 /bin/sh .git/hooks/post-commit
+`
+	chain := parsePSAncestors(out, 800)
+	if len(chain) != 4 {
+		t.Fatalf("got %d ancestors, want 4: %+v", len(chain), chain)
+	}
+	tool, ok := agentFromAncestors(chain)
+	if !ok || tool != ToolCodex {
+		t.Errorf("got %q, %v; want codex", tool, ok)
+	}
+}
+
+func TestMarkAgentCommit(t *testing.T) {
+	detections := []Detection{
+		{Tool: ToolCursor, Method: MethodInlineCompletion, FilesMatched: []string{"a.go"}},
+		{Tool: ToolCursor, Method: MethodFileMatch, FilesMatched: []string{"b.go"}},
+		{Tool: ToolClaudeCode, Method: MethodFileMatch},
+	}
+	if !markAgentCommit(detections, ToolCursor) {
+		t.Fatal("cursor was detected")
+	}
+	want := []Method{MethodInlineCompletion, MethodAgentCommit, MethodFileMatch}
+	for i, d := range detections {
+		if d.Method != want[i] {
+			t.Errorf("detection %d: got %s, want %s", i, d.Method, want[i])
+		}
+	}
+
+	// Inline completions alone stay as they are
+	inline := []Detection{{Tool: ToolCursor, Method: MethodInlineCompletion}}
+	if !markAgentCommit(inline, ToolCursor) || inline[0].Method != MethodInlineCompletion {
+		t.Errorf("got %+v, want the inline detection kept", inline)
+	}
+	if markAgentCommit(inline, ToolCodex) {
+		t.Error("codex was not detected")
+	}
+}
@@ -4,12 +4,84 @@ import (
 	"bufio"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
 
+// Aider chat history parsing.
+//
+// Aider appends every session to .aider.chat.history.md in the repo root:
+//
+//   # aider chat started at 2026-02-12 10:00:00
+//
+//   > Model: gpt-4o with diff edit format
+//
+//   #### add a hello function              ← user prompt, not a file
+//
+//   src/main.go                            ← edit block: file name, then
+//   ```go                                    SEARCH/REPLACE markers
+//   <<<<<<< SEARCH
+//   =======
+//   func hello() {}
+//   >>>>>>> REPLACE
+//   ```
+//
+//   > Tokens: 4.2k sent, 1.1k received. Cost: $0.02 message, $0.05 session.
+//   > Applied edit to src/main.go
+//
+// "Applied edit to" lines are the authoritative record of what was written.
+// Edit blocks only count for a response that has no such lines and no
+// report of the edit failing to apply.
+
+var (
+	aiderSessionRe = regexp.MustCompile(`^# aider chat started at (\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2})`)
+	aiderAppliedRe = regexp.MustCompile(`^> Applied edit to (.+)$`)
+	aiderModelRe   = regexp.MustCompile(`^> (?:Main )?[Mm]odel: (\S+)`)
+	aiderSentRe    = regexp.MustCompile(`([\d.,]+[kKmM]?) sent`)
+	aiderRecvRe    = regexp.MustCompile(`([\d.,]+[kKmM]?) received`)
+
+	// Co-authored-by: aider (gpt-4o) <noreply@aider.chat>
+	aiderCoAuthorRe = regexp.MustCompile(`(?i)^co-authored-by:\s*aider\b(?:\s*\(([^)]*)\))?`)
+)
+
+// aiderEditFailures mark a response whose edit blocks were not applied.
+var aiderEditFailures = []string{
+	"did not conform to the edit format",
+	"Failed to apply edit",
+	"SearchReplaceNoExactMatch",
+}
+
+// aiderSession accumulates one "# aider chat started at" section.
+type aiderSession struct {
+	start  time.Time
+	files  map[string]struct{}
+	model  string
+	tokens int64
+
+	// Per-response state, flushed at each prompt
+	blockFiles []string
+	applied    bool
+	failed     bool
+}
+
+// flushResponse falls back to edit-block file names when the response
+// recorded no applied edits and no failures.
+func (s *aiderSession) flushResponse() {
+	if !s.applied && !s.failed {
+		for _, f := range s.blockFiles {
+			s.files[f] = struct{}{}
+		}
+	}
+	s.blockFiles = nil
+	s.applied = false
+	s.failed = false
+}
+
 // detectAider parses .aider.chat.history.md in the repo root and extracts
-// file paths from #### headers.
+// the files Aider edited in sessions within maxAge, along with the model
+// and token usage they reported.
 func detectAider(repoRoot string, maxAge time.Duration) (*SessionInfo, error) {
 	historyPath := filepath.Join(repoRoot, ".aider.chat.history.md")
 	f, err := os.Open(historyPath)
@@ -29,24 +101,174 @@ func detectAider(repoRoot string, maxAge time.Duration) (*SessionInfo, error) {
 		return nil, nil
 	}
 
+	sessions, err := parseAiderHistory(f, repoRoot)
+	if err != nil {
+		return nil, err
+	}
+
 	info := &SessionInfo{
 		Tool:         ToolAider,
 		FilesWritten: make(map[string]struct{}),
 	}
 
+	// The last session runs up to the file's modification time, so it is
+	// recent even if it started long ago; earlier ones must start within maxAge
+	cutoff := time.Now().Add(-maxAge)
+	var earliest time.Time
+	for i, s := range sessions {
+		if i != len(sessions)-1 && !s.start.After(cutoff) {
+			continue
+		}
+		for file := range s.files {
+			info.FilesWritten[file] = struct{}{}
+		}
+		if s.model != "" {
+			info.Model = s.model
+		}
+		info.TotalTokens += s.tokens
+		if !s.start.IsZero() && (earliest.IsZero() || s.start.Before(earliest)) {
+			earliest = s.start
+		}
+	}
+
+	if len(info.FilesWritten) == 0 {
+		return nil, nil
+	}
+	if !earliest.IsZero() && stat.ModTime().After(earliest) {
+		info.SessionDurationSec = int64(stat.ModTime().Sub(earliest).Seconds())
+	}
+	info.LastActivity = stat.ModTime()
+	return info, nil
+}
+
+// parseAiderHistory splits a chat history into sessions and collects the
+// edits, model and token counts each one reports.
+func parseAiderHistory(f *os.File, repoRoot string) ([]*aiderSession, error) {
+	var sessions []*aiderSession
+	newSession := func(start time.Time) *aiderSession {
+		s := &aiderSession{start: start, files: make(map[string]struct{})}
+		sessions = append(sessions, s)
+		return s
+	}
+	var cur *aiderSession
+
+	// The two most recent non-blank lines, to find an edit block's file name
+	var prev, prev2 string
+
 	scanner := bufio.NewScanner(f)
+	buf := make([]byte, 0, 1024*1024)
+	scanner.Buffer(buf, 10*1024*1024)
 	for scanner.Scan() {
 		line := scanner.Text()
-		if strings.HasPrefix(line, "#### ") {
-			filePath := strings.TrimSpace(strings.TrimPrefix(line, "#### "))
-			if filePath != "" && !strings.Contains(filePath, " ") {
-				info.FilesWritten[filePath] = struct{}{}
+
+		if m := aiderSessionRe.FindStringSubmatch(line); m != nil {
+			if cur != nil {
+				cur.flushResponse()
 			}
+			start, _ := time.ParseInLocation("2006-01-02 15:04:05", m[1], time.Local)
+			cur = newSession(start)
+			prev, prev2 = "", ""
+			continue
+		}
+		if cur == nil {
+			cur = newSession(time.Time{})
+		}
+
+		switch {
+		case strings.HasPrefix(line, "#### "):
+			cur.flushResponse()
+		case aiderAppliedRe.MatchString(line):
+			if file := aiderFilePath(aiderAppliedRe.FindStringSubmatch(line)[1], repoRoot); file != "" {
+				cur.files[file] = struct{}{}
+			}
+			cur.applied = true
+		case aiderModelRe.MatchString(line):
+			cur.model = aiderModelRe.FindStringSubmatch(line)[1]
+		case strings.HasPrefix(line, "> Tokens: "):
+			cur.tokens += aiderTokenCount(aiderSentRe, line) + aiderTokenCount(aiderRecvRe, line)
+		case strings.TrimSpace(line) == "<<<<<<< SEARCH":
+			name := prev
+			if strings.HasPrefix(prev, "```") {
+				name = prev2
+			}
+			if file := aiderFilePath(name, repoRoot); file != "" {
+				cur.blockFiles = append(cur.blockFiles, file)
+			}
+		default:
+			for _, marker := range aiderEditFailures {
+				if strings.Contains(line, marker) {
+					cur.failed = true
+					break
+				}
+			}
+		}
+
+		if trimmed := strings.TrimSpace(line); trimmed != "" {
+			prev2, prev = prev, trimmed
 		}
 	}
+	if cur != nil {
+		cur.flushResponse()
+	}
+	return sessions, scanner.Err()
+}
 
-	if len(info.FilesWritten) == 0 {
-		return nil, nil
+// aiderFilePath cleans a file name from the history and returns it relative
+// to the repo, or "" if it does not look like a path inside the repo.
+func aiderFilePath(name, repoRoot string) string {
+	name = strings.TrimSpace(name)
+	name = strings.Trim(name, "`*")
+	if name == "" || strings.ContainsAny(name, " \t") || strings.HasPrefix(name, ">") {
+		return ""
+	}
+	if filepath.IsAbs(name) {
+		rel, ok := repoRelPath(filepath.Clean(name), repoRoot)
+		if !ok {
+			return ""
+		}
+		return filepath.ToSlash(rel)
+	}
+	return filepath.ToSlash(filepath.Clean(name))
+}
+
+// aiderTokenCount extracts a count such as "4.2k", "1,234" or "1.1M"
+// captured by re from a "> Tokens:" report.
+func aiderTokenCount(re *regexp.Regexp, line string) int64 {
+	m := re.FindStringSubmatch(line)
+	if m == nil {
+		return 0
+	}
+	s := strings.ReplaceAll(m[1], ",", "")
+	mult := 1.0
+	switch s[len(s)-1] {
+	case 'k', 'K':
+		mult = 1e3
+		s = s[:len(s)-1]
+	case 'm', 'M':
+		mult = 1e6
+		s = s[:len(s)-1]
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return int64(n*mult + 0.5)
+}
+
+// aiderAutoCommit reports whether a commit was made by Aider itself. Aider
+// appends " (aider)" to the author name of the changes it wrote and can add
+// a "Co-authored-by: aider (<model>)" trailer; the model named in that
+// trailer is returned when present. A marked committer alone doesn't
+// count: Aider commits the user's own dirty changes under their name with
+// only the committer marked.
+func aiderAutoCommit(authorName, commitMsg string) (model string, ok bool) {
+	for _, line := range strings.Split(commitMsg, "\n") {
+		if m := aiderCoAuthorRe.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+			return strings.TrimSpace(m[1]), true
+		}
+	}
+	if strings.HasSuffix(strings.TrimSpace(authorName), "(aider)") {
+		return "", true
 	}
-	return info, scanner.Err()
+	return "", false
 }
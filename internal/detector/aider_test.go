@@ -2,6 +2,7 @@ package detector
 
 import (
 	"os"
+	"os/exec"
 	"path/filepath"
 	"testing"
 	"time"
@@ -98,3 +99,218 @@ func TestDetectAider_NoFilePaths(t *testing.T) {
 		t.Errorf("expected nil for no file paths, got %+v", info)
 	}
 }
+
+func TestDetectAider_PromptsAreNotFiles(t *testing.T) {
+	dir := t.TempDir()
+	content := `# aider chat started at 2026-02-12 10:00:00
+
+#### refactor.go
+
+I can't find that file. Please add it to the chat.
+`
+	if err := os.WriteFile(filepath.Join(dir, ".aider.chat.history.md"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := detectAider(dir, 72*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info != nil {
+		t.Errorf("expected nil for prompt-only history, got %+v", info)
+	}
+}
+
+func TestDetectAider_EditBlocks(t *testing.T) {
+	dir := t.TempDir()
+	content := "# aider chat started at 2026-02-12 10:00:00\n\n" +
+		"#### add a hello function\n\n" +
+		"src/hello.go\n```go\n<<<<<<< SEARCH\n=======\nfunc hello() {}\n>>>>>>> REPLACE\n```\n\n" +
+		"#### now a broken one\n\n" +
+		"src/broken.go\n```go\n<<<<<<< SEARCH\nfoo\n=======\nbar\n>>>>>>> REPLACE\n```\n\n" +
+		"> The LLM did not conform to the edit format.\n"
+	if err := os.WriteFile(filepath.Join(dir, ".aider.chat.history.md"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := detectAider(dir, 72*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info == nil {
+		t.Fatal("expected non-nil info")
+	}
+	if got := sortedKeys(info.FilesWritten); !equal(got, []string{"src/hello.go"}) {
+		t.Errorf("files: got %v, want [src/hello.go]", got)
+	}
+}
+
+func TestDetectAider_Sessions(t *testing.T) {
+	dir := t.TempDir()
+	stale := time.Now().Add(-10 * 24 * time.Hour).Format("2006-01-02 15:04:05")
+	recent := time.Now().Add(-30 * time.Minute).Format("2006-01-02 15:04:05")
+	content := "# aider chat started at " + stale + "\n\n" +
+		"> Model: gpt-4o with diff edit format\n\n" +
+		"#### old change\n\n" +
+		"> Tokens: 9k sent, 1k received. Cost: $0.05 message, $0.05 session.\n" +
+		"> Applied edit to old.go\n\n" +
+		"# aider chat started at " + recent + "\n\n" +
+		"> Main model: claude-3-5-sonnet-20241022 with diff edit format, infinite output\n" +
+		"> Weak model: claude-3-5-haiku-20241022\n\n" +
+		"#### add auth\n\n" +
+		"> Tokens: 4.2k sent, 1,100 received. Cost: $0.02 message, $0.02 session.\n" +
+		"> Applied edit to src/auth.go\n\n" +
+		"#### and tests\n\n" +
+		"> Tokens: 1.5k sent, 2.1k cache write, 300 received.\n" +
+		"> Applied edit to " + filepath.Join(dir, "src", "auth_test.go") + "\n"
+	if err := os.WriteFile(filepath.Join(dir, ".aider.chat.history.md"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := detectAider(dir, 72*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info == nil {
+		t.Fatal("expected non-nil info")
+	}
+
+	wantFiles := []string{"src/auth.go", "src/auth_test.go"}
+	if got := sortedKeys(info.FilesWritten); !equal(got, wantFiles) {
+		t.Errorf("files: got %v, want %v", got, wantFiles)
+	}
+	if info.Model != "claude-3-5-sonnet-20241022" {
+		t.Errorf("model: got %q", info.Model)
+	}
+	if info.TotalTokens != 4200+1100+1500+300 {
+		t.Errorf("tokens: got %d, want 7100", info.TotalTokens)
+	}
+	if info.SessionDurationSec < 29*60 || info.SessionDurationSec > 31*60 {
+		t.Errorf("duration: got %ds, want ~1800s", info.SessionDurationSec)
+	}
+}
+
+func TestAiderAutoCommit(t *testing.T) {
+	tests := []struct {
+		name      string
+		author    string
+		msg       string
+		wantModel string
+		wantOK    bool
+	}{
+		{"author marked", "Jane Doe (aider)", "feat: add hello\n", "", true},
+		{"co-authored-by trailer", "Jane Doe", "feat: add auth\n\nCo-authored-by: aider (anthropic/claude-3-7-sonnet-20250219) <noreply@aider.chat>\n", "anthropic/claude-3-7-sonnet-20250219", true},
+		{"trailer without model", "Jane Doe", "feat: x\n\nCo-authored-by: aider <noreply@aider.chat>\n", "", true},
+		{"trailer and marked author", "Jane Doe (aider)", "feat: x\n\nCo-authored-by: aider (gpt-4o) <noreply@aider.chat>\n", "gpt-4o", true},
+		{"human commit", "Jane Doe", "feat: mention aider in passing\n", "", false},
+		{"other co-author", "Jane Doe", "feat: x\n\nCo-authored-by: aiden <aiden@example.com>\n", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			model, ok := aiderAutoCommit(tt.author, tt.msg)
+			if model != tt.wantModel || ok != tt.wantOK {
+				t.Errorf("got %q, %v; want %q, %v", model, ok, tt.wantModel, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestDetect_AiderAutoCommit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	t.Setenv("HOME", t.TempDir())
+
+	repoRoot := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repoRoot
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=Jane Doe (aider)", "GIT_AUTHOR_EMAIL=jane@example.com",
+			"GIT_COMMITTER_NAME=Jane Doe (aider)", "GIT_COMMITTER_EMAIL=jane@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q")
+	git("commit", "-q", "--allow-empty", "-m", "initial")
+	for _, name := range []string{"a.go", "b.go"} {
+		if err := os.WriteFile(filepath.Join(repoRoot, name), []byte("package a\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git("add", "a.go", "b.go")
+	git("commit", "-q", "-m", "feat: add a and b\n\nCo-authored-by: aider (gpt-4o) <noreply@aider.chat>")
+
+	attr, err := Detect(repoRoot, Options{SessionRoots: SessionRoots{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attr == nil {
+		t.Fatal("expected attribution")
+	}
+	var found *Detection
+	for i := range attr.Detections {
+		if attr.Detections[i].Tool == ToolAider {
+			if found != nil {
+				t.Fatalf("aider detected twice: %+v", attr.Detections)
+			}
+			found = &attr.Detections[i]
+		}
+	}
+	if found == nil {
+		t.Fatalf("no aider detection in %+v", attr.Detections)
+	}
+	if found.Method != MethodAutoCommit || found.Confidence != ConfidenceHigh {
+		t.Errorf("got %s/%s, want auto-commit/high", found.Method, found.Confidence)
+	}
+	if found.AIFiles != 2 || found.FilesCommitted != 2 || !equal(found.FilesMatched, []string{"a.go", "b.go"}) {
+		t.Errorf("files: got %+v", found)
+	}
+	if found.Model != "gpt-4o" {
+		t.Errorf("model: got %q", found.Model)
+	}
+}
+
+func TestDetect_AiderDirtyCommit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	t.Setenv("HOME", t.TempDir())
+
+	// Aider commits the user's own uncommitted changes before editing,
+	// marking only the committer
+	repoRoot := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repoRoot
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=Jane Doe", "GIT_AUTHOR_EMAIL=jane@example.com",
+			"GIT_COMMITTER_NAME=Jane Doe (aider)", "GIT_COMMITTER_EMAIL=jane@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q")
+	git("commit", "-q", "--allow-empty", "-m", "initial")
+	if err := os.WriteFile(filepath.Join(repoRoot, "a.go"), []byte("package a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git("add", "a.go")
+	git("commit", "-q", "-m", "wip")
+
+	attr, err := Detect(repoRoot, Options{SessionRoots: SessionRoots{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attr == nil {
+		return
+	}
+	for _, d := range attr.Detections {
+		if d.Method == MethodAutoCommit {
+			t.Errorf("got an auto-commit detection for the user's own changes: %+v", d)
+		}
+	}
+}
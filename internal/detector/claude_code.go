@@ -41,16 +41,12 @@ type jsonlUsage struct {
 	CacheReadInputTokens     int64 `json:"cache_read_input_tokens"`
 }
 
-// claudeSessionDir returns the Claude Code projects directory for a given repo root.
+// claudeSessionDir returns the Claude Code session directory for a given repo
+// root under a projects directory.
 // e.g. /Users/jose/projects/tempo → ~/.claude/projects/-Users-jose-projects-tempo
-// Returns empty string if the home directory cannot be determined.
-func claudeSessionDir(repoRoot string) string {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return ""
-	}
+func claudeSessionDir(projectsDir, repoRoot string) string {
 	encoded := strings.ReplaceAll(repoRoot, string(filepath.Separator), "-")
-	return filepath.Join(homeDir, ".claude", "projects", encoded)
+	return filepath.Join(projectsDir, encoded)
 }
 
 // findRecentSessions returns all .jsonl files in the session dir modified
@@ -174,6 +170,7 @@ func parseClaudeSession(jsonlPath string, repoRoot string) (*SessionInfo, error)
 	if !firstTimestamp.IsZero() && !lastTimestamp.IsZero() {
 		info.SessionDurationSec = int64(lastTimestamp.Sub(firstTimestamp).Seconds())
 	}
+	info.LastActivity = lastTimestamp
 
 	return info, scanner.Err()
 }
@@ -140,7 +140,7 @@ func TestParseClaudeSession_ModelUpdate(t *testing.T) {
 }
 
 func TestClaudeSessionDir(t *testing.T) {
-	dir := claudeSessionDir("/Users/jose/projects/tempo")
+	dir := claudeSessionDir(claudeProjectsDirs()[0], "/Users/jose/projects/tempo")
 	if !filepath.IsAbs(dir) {
 		t.Errorf("expected absolute path, got %q", dir)
 	}
@@ -148,35 +148,33 @@ func cleanPath(p string) string {
 }
 
 // findCodexSessions finds all Codex session files for a given repo root.
-// Sessions are stored at ~/.codex/sessions/YYYY/MM/DD/rollout-*.jsonl.
+// Sessions are stored at {root}/YYYY/MM/DD/rollout-*.jsonl, where each root is
+// a sessions directory such as ~/.codex/sessions.
 // Only returns sessions modified within maxAge whose cwd matches the repo root.
-func findCodexSessions(repoRoot string, maxAge time.Duration) ([]string, error) {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return nil, nil
-	}
-
-	sessionsDir := filepath.Join(homeDir, ".codex", "sessions")
-	if _, err := os.Stat(sessionsDir); os.IsNotExist(err) {
-		return nil, nil
-	}
-
+func findCodexSessions(repoRoot string, roots []string, maxAge time.Duration) ([]string, error) {
 	cutoff := time.Now().Add(-maxAge)
-	pattern := filepath.Join(sessionsDir, "*", "*", "*", "rollout-*.jsonl")
-	matches, err := filepath.Glob(pattern)
-	if err != nil {
-		return nil, nil
-	}
-
 	var sessions []string
-	for _, path := range matches {
-		info, err := os.Stat(path)
-		if err != nil || info.ModTime().Before(cutoff) {
+
+	for _, sessionsDir := range roots {
+		if _, err := os.Stat(sessionsDir); os.IsNotExist(err) {
+			continue
+		}
+
+		pattern := filepath.Join(sessionsDir, "*", "*", "*", "rollout-*.jsonl")
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
 			continue
 		}
-		// Quick check: read first line to verify cwd matches
-		if matchesRepo(path, repoRoot) {
-			sessions = append(sessions, path)
+
+		for _, path := range matches {
+			info, err := os.Stat(path)
+			if err != nil || info.ModTime().Before(cutoff) {
+				continue
+			}
+			// Quick check: read first line to verify cwd matches
+			if matchesRepo(path, repoRoot) {
+				sessions = append(sessions, path)
+			}
 		}
 	}
 	return sessions, nil
@@ -310,13 +308,14 @@ func parseCodexSession(jsonlPath string) (*SessionInfo, error) {
 	if !firstTimestamp.IsZero() && !lastTimestamp.IsZero() {
 		info.SessionDurationSec = int64(lastTimestamp.Sub(firstTimestamp).Seconds())
 	}
+	info.LastActivity = lastTimestamp
 
 	return info, scanner.Err()
 }
 
 // detectCodex finds recent Codex sessions for the repo and merges their file sets.
-func detectCodex(repoRoot string, maxAge time.Duration) (*SessionInfo, error) {
-	sessions, err := findCodexSessions(repoRoot, maxAge)
+func detectCodex(repoRoot string, roots []string, maxAge time.Duration) (*SessionInfo, error) {
+	sessions, err := findCodexSessions(repoRoot, roots, maxAge)
 	if err != nil || len(sessions) == 0 {
 		return nil, nil
 	}
@@ -344,6 +343,9 @@ func detectCodex(repoRoot string, maxAge time.Duration) (*SessionInfo, error) {
 		if session.SessionDurationSec > merged.SessionDurationSec {
 			merged.SessionDurationSec = session.SessionDurationSec
 		}
+		if session.LastActivity.After(merged.LastActivity) {
+			merged.LastActivity = session.LastActivity
+		}
 	}
 
 	if len(merged.FilesWritten) == 0 {
@@ -369,7 +369,7 @@ func TestFindCodexSessions(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	sessions, err := findCodexSessions(repoRoot, 72*time.Hour)
+	sessions, err := findCodexSessions(repoRoot, codexSessionsDirs(), 72*time.Hour)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -386,7 +386,7 @@ func TestFindCodexSessions_NoSessionsDir(t *testing.T) {
 	homeDir := t.TempDir()
 	t.Setenv("HOME", homeDir)
 
-	sessions, err := findCodexSessions("/some/repo", 72*time.Hour)
+	sessions, err := findCodexSessions("/some/repo", codexSessionsDirs(), 72*time.Hour)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -423,7 +423,7 @@ func TestDetectCodex_MergesSessions(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	info, err := detectCodex(repoRoot, 72*time.Hour)
+	info, err := detectCodex(repoRoot, codexSessionsDirs(), 72*time.Hour)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1,11 +1,12 @@
 package detector
 
 import (
+	"bufio"
 	"encoding/json"
 	"net/url"
 	"os"
 	"path/filepath"
-	"runtime"
+	"sort"
 	"strings"
 	"time"
 )
@@ -16,6 +17,16 @@ import (
 //   macOS:  ~/Library/Application Support/Code/User/workspaceStorage/{hash}/chatSessions/{uuid}.json
 //   Linux:  ~/.config/Code/User/workspaceStorage/{hash}/chatSessions/{uuid}.json
 //
+// Newer VS Code builds write the same session as an append-only operation log
+// at chatSessions/{uuid}.jsonl instead: the first line holds the initial
+// session ({"kind":0,"v":{...}}) and each following line sets (kind 1), pushes
+// onto (kind 2) or deletes (kind 3) the value at key path "k".
+//
+// Agent edits are tracked per session in
+//   workspaceStorage/{hash}/chatEditingSessions/{uuid}/state.json
+// whose snapshot entries carry the file URI and the user's decision
+// (0 = pending, 1 = accepted, 2 = rejected).
+//
 // Workspace-to-repo mapping:
 //   ~/Library/Application Support/Code/User/workspaceStorage/{hash}/workspace.json
 //   → {"folder": "file:///path/to/repo"}
@@ -23,18 +34,24 @@ import (
 // File edits appear as response parts with kind "textEditGroup":
 //   {"kind": "textEditGroup", "uri": {"path": "/abs/path/to/file"}, "edits": [...]}
 //
+// Requests may also list editedFileEvents recording whether the user kept
+// (eventKind 1) or undid (eventKind 2) an edit.
+//
 // Agent mode is identified by requests[].agent.id containing "editsAgent" or "workspace".
 
 type copilotSession struct {
+	SessionID     string                `json:"sessionId"`
 	Requests      []copilotRequest      `json:"requests"`
 	SelectedModel *copilotSelectedModel `json:"selectedModel"`
 }
 
 type copilotRequest struct {
-	Timestamp int64              `json:"timestamp"` // unix ms
-	ModelID   string             `json:"modelId"`
-	Agent     *copilotAgent      `json:"agent"`
-	Response  []copilotRespPart  `json:"response"`
+	Timestamp        int64                    `json:"timestamp"` // unix ms
+	ModelID          string                   `json:"modelId"`
+	Agent            *copilotAgent            `json:"agent"`
+	Response         []copilotRespPart        `json:"response"`
+	Result           *copilotResult           `json:"result"`
+	EditedFileEvents []copilotEditedFileEvent `json:"editedFileEvents"`
 }
 
 type copilotAgent struct {
@@ -50,6 +67,30 @@ type copilotURI struct {
 	Path string `json:"path"`
 }
 
+type copilotResult struct {
+	Metadata *copilotResultMetadata `json:"metadata"`
+	Usage    *copilotUsage          `json:"usage"`
+}
+
+type copilotResultMetadata struct {
+	ResolvedModel string `json:"resolvedModel"`
+	PromptTokens  int64  `json:"promptTokens"`
+	OutputTokens  int64  `json:"outputTokens"`
+}
+
+type copilotUsage struct {
+	PromptTokens     int64 `json:"promptTokens"`
+	CompletionTokens int64 `json:"completionTokens"`
+}
+
+// copilotEditedFileEvent records a user decision on an agent edit.
+type copilotEditedFileEvent struct {
+	URI       copilotURI `json:"uri"`
+	EventKind int        `json:"eventKind"` // 1=keep, 2=undo, 3=user modification
+}
+
+const copilotEditEventUndo = 2
+
 type copilotSelectedModel struct {
 	Identifier string                `json:"identifier"`
 	Metadata   *copilotModelMetadata `json:"metadata"`
@@ -60,68 +101,62 @@ type copilotModelMetadata struct {
 	Version string `json:"version"`
 }
 
-type copilotWorkspace struct {
-	Folder string `json:"folder"` // "file:///path/to/repo"
+// copilotLogOp is one line of a chatSessions/*.jsonl operation log.
+type copilotLogOp struct {
+	Kind int             `json:"kind"` // 0=initial, 1=set, 2=push, 3=delete
+	Key  []any           `json:"k"`
+	V    json.RawMessage `json:"v"`
 }
 
-// vscodeBaseDirs returns the VS Code workspace storage base directories
-// for the current OS. Checks both VS Code and VS Code Insiders.
-func vscodeBaseDirs() []string {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return nil
-	}
+// copilotEditingState is the subset of chatEditingSessions/{uuid}/state.json we read.
+type copilotEditingState struct {
+	RecentSnapshot *copilotEditSnapshot     `json:"recentSnapshot"`
+	LinearHistory  []copilotEditHistoryItem `json:"linearHistory"`
+}
 
-	var bases []string
-	switch runtime.GOOS {
-	case "darwin":
-		appSupport := filepath.Join(homeDir, "Library", "Application Support")
-		bases = []string{
-			filepath.Join(appSupport, "Code", "User", "workspaceStorage"),
-			filepath.Join(appSupport, "Code - Insiders", "User", "workspaceStorage"),
-		}
-	case "linux":
-		configDir := filepath.Join(homeDir, ".config")
-		bases = []string{
-			filepath.Join(configDir, "Code", "User", "workspaceStorage"),
-			filepath.Join(configDir, "Code - Insiders", "User", "workspaceStorage"),
-		}
-	}
-	return bases
+type copilotEditHistoryItem struct {
+	Stops []copilotEditSnapshot `json:"stops"`
 }
 
-// findCopilotWorkspace finds the VS Code workspace storage directory
-// whose workspace.json maps to the given repo root.
-func findCopilotWorkspace(repoRoot string) string {
-	for _, baseDir := range vscodeBaseDirs() {
-		entries, err := os.ReadDir(baseDir)
-		if err != nil {
-			continue
-		}
-		for _, entry := range entries {
-			if !entry.IsDir() {
-				continue
-			}
-			wsPath := filepath.Join(baseDir, entry.Name(), "workspace.json")
-			data, err := os.ReadFile(wsPath)
-			if err != nil {
-				continue
-			}
-			var ws copilotWorkspace
-			if err := json.Unmarshal(data, &ws); err != nil {
-				continue
-			}
-			folder := uriToPath(ws.Folder)
-			if folder == repoRoot {
-				return filepath.Join(baseDir, entry.Name())
-			}
-		}
-	}
-	return ""
+type copilotEditSnapshot struct {
+	Entries []copilotEditEntry `json:"entries"`
 }
 
-// uriToPath converts a file:// URI to a local path.
+type copilotEditEntry struct {
+	Resource      string                `json:"resource"` // "file:///path/to/file"
+	State         int                   `json:"state"`
+	TelemetryInfo *copilotEditTelemetry `json:"telemetryInfo"`
+}
+
+type copilotEditTelemetry struct {
+	RequestID string `json:"requestId"`
+	ModelID   string `json:"modelId"`
+}
+
+// Editing session entry states.
+const (
+	copilotEditPending  = 0
+	copilotEditAccepted = 1
+	copilotEditRejected = 2
+)
+
+// uriToPath converts a file:// or vscode-remote:// URI to a local path.
+// Remote URIs (e.g. vscode-remote://ssh-remote%2Bhost/home/me/repo) name a
+// path on the remote host, which is local when running inside that host.
+// Their authority is percent-encoded, which net/url rejects, so it is
+// dropped before unescaping the path.
 func uriToPath(uri string) string {
+	if strings.HasPrefix(uri, "vscode-remote://") {
+		rest := strings.TrimPrefix(uri, "vscode-remote://")
+		i := strings.Index(rest, "/")
+		if i < 0 {
+			return ""
+		}
+		if p, err := url.PathUnescape(rest[i:]); err == nil {
+			return p
+		}
+		return rest[i:]
+	}
 	if !strings.HasPrefix(uri, "file://") {
 		return uri
 	}
@@ -132,7 +167,8 @@ func uriToPath(uri string) string {
 	return u.Path
 }
 
-// findCopilotSessions finds recent chat session JSON files in the workspace dir.
+// findCopilotSessions finds recent chat session files (.json or .jsonl) in
+// the workspace dir.
 func findCopilotSessions(workspaceDir string, maxAge time.Duration) ([]string, error) {
 	chatDir := filepath.Join(workspaceDir, "chatSessions")
 	entries, err := os.ReadDir(chatDir)
@@ -143,29 +179,168 @@ func findCopilotSessions(workspaceDir string, maxAge time.Duration) ([]string, e
 	cutoff := time.Now().Add(-maxAge)
 	var sessions []string
 	for _, entry := range entries {
-		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+		name := entry.Name()
+		if entry.IsDir() || (!strings.HasSuffix(name, ".json") && !strings.HasSuffix(name, ".jsonl")) {
 			continue
 		}
 		info, err := entry.Info()
 		if err != nil || info.ModTime().Before(cutoff) {
 			continue
 		}
-		sessions = append(sessions, filepath.Join(chatDir, entry.Name()))
+		sessions = append(sessions, filepath.Join(chatDir, name))
 	}
 	return sessions, nil
 }
 
-// parseCopilotSession reads a Copilot chat session JSON file and extracts
+// findCopilotEditingSessions returns recent chatEditingSessions/{uuid}/state.json
+// files in the workspace dir, keyed by session ID.
+func findCopilotEditingSessions(workspaceDir string, maxAge time.Duration) map[string]string {
+	editDir := filepath.Join(workspaceDir, "chatEditingSessions")
+	entries, err := os.ReadDir(editDir)
+	if err != nil {
+		return nil
+	}
+
+	cutoff := time.Now().Add(-maxAge)
+	sessions := make(map[string]string)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		statePath := filepath.Join(editDir, entry.Name(), "state.json")
+		info, err := os.Stat(statePath)
+		if err != nil || info.ModTime().Before(cutoff) {
+			continue
+		}
+		sessions[entry.Name()] = statePath
+	}
+	return sessions
+}
+
+// decodeCopilotSession decodes a chat session in either the legacy JSON format
+// or, for .jsonl files, the operation log format.
+func decodeCopilotSession(path string, data []byte) (*copilotSession, error) {
+	if strings.HasSuffix(path, ".jsonl") {
+		var err error
+		data, err = replayCopilotLog(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var session copilotSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// replayCopilotLog applies a chat session operation log and returns the
+// resulting session document as JSON. Malformed operations are skipped.
+func replayCopilotLog(data []byte) ([]byte, error) {
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	buf := make([]byte, 0, 1024*1024)
+	scanner.Buffer(buf, 64*1024*1024)
+
+	var root any
+	for scanner.Scan() {
+		var op copilotLogOp
+		if err := json.Unmarshal(scanner.Bytes(), &op); err != nil {
+			continue
+		}
+		var v any
+		if len(op.V) > 0 {
+			if err := json.Unmarshal(op.V, &v); err != nil {
+				continue
+			}
+		}
+
+		switch op.Kind {
+		case 0:
+			root = v
+		case 1:
+			root = updateJSONPath(root, op.Key, func(any) any { return v })
+		case 2:
+			items, _ := v.([]any)
+			root = updateJSONPath(root, op.Key, func(old any) any {
+				arr, _ := old.([]any)
+				return append(arr, items...)
+			})
+		case 3:
+			if len(op.Key) == 0 {
+				root = nil
+				continue
+			}
+			last := op.Key[len(op.Key)-1]
+			root = updateJSONPath(root, op.Key[:len(op.Key)-1], func(parent any) any {
+				return deleteJSONKey(parent, last)
+			})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return json.Marshal(root)
+}
+
+// updateJSONPath replaces the value at path within a decoded JSON tree with
+// fn(old), creating intermediate objects as needed. Path elements are object
+// keys (string) or array indices (number).
+func updateJSONPath(node any, path []any, fn func(any) any) any {
+	if len(path) == 0 {
+		return fn(node)
+	}
+	switch key := path[0].(type) {
+	case string:
+		obj, ok := node.(map[string]any)
+		if !ok {
+			obj = make(map[string]any)
+		}
+		obj[key] = updateJSONPath(obj[key], path[1:], fn)
+		return obj
+	case float64:
+		arr, _ := node.([]any)
+		idx := int(key)
+		if idx < 0 {
+			return node
+		}
+		for len(arr) <= idx {
+			arr = append(arr, nil)
+		}
+		arr[idx] = updateJSONPath(arr[idx], path[1:], fn)
+		return arr
+	}
+	return node
+}
+
+// deleteJSONKey removes key from a decoded JSON object or array.
+func deleteJSONKey(node any, key any) any {
+	switch k := key.(type) {
+	case string:
+		if obj, ok := node.(map[string]any); ok {
+			delete(obj, k)
+		}
+	case float64:
+		if arr, ok := node.([]any); ok {
+			if idx := int(k); idx >= 0 && idx < len(arr) {
+				return append(arr[:idx], arr[idx+1:]...)
+			}
+		}
+	}
+	return node
+}
+
+// parseCopilotSession reads a Copilot chat session file and extracts
 // file-level edit information. Only returns data if the session contains
-// textEditGroup entries (Agent mode file edits).
+// textEditGroup entries (Agent mode file edits) that the user did not undo.
 func parseCopilotSession(jsonPath string, repoRoot string) (*SessionInfo, error) {
 	data, err := os.ReadFile(jsonPath)
 	if err != nil {
 		return nil, err
 	}
 
-	var session copilotSession
-	if err := json.Unmarshal(data, &session); err != nil {
+	session, err := decodeCopilotSession(jsonPath, data)
+	if err != nil {
 		return nil, nil
 	}
 
@@ -175,13 +350,8 @@ func parseCopilotSession(jsonPath string, repoRoot string) (*SessionInfo, error)
 	}
 
 	var firstTimestamp, lastTimestamp int64
-
-	// Extract model from selectedModel metadata
-	if session.SelectedModel != nil && session.SelectedModel.Metadata != nil {
-		if family := session.SelectedModel.Metadata.Family; family != "" {
-			info.Model = family
-		}
-	}
+	var requestModel string
+	undone := make(map[string]struct{})
 
 	for _, req := range session.Requests {
 		// Track timestamps for session duration
@@ -194,9 +364,20 @@ func parseCopilotSession(jsonPath string, repoRoot string) (*SessionInfo, error)
 			}
 		}
 
-		// Fall back to per-request modelId if no selectedModel
-		if info.Model == "" && req.ModelID != "" {
-			info.Model = req.ModelID
+		// Per-request model: the resolved model wins over the picker's modelId
+		if req.Result != nil && req.Result.Metadata != nil && req.Result.Metadata.ResolvedModel != "" {
+			requestModel = req.Result.Metadata.ResolvedModel
+		} else if req.ModelID != "" {
+			requestModel = req.ModelID
+		}
+
+		// Per-request token usage, where the response recorded it
+		if req.Result != nil {
+			if u := req.Result.Usage; u != nil {
+				info.TotalTokens += u.PromptTokens + u.CompletionTokens
+			} else if m := req.Result.Metadata; m != nil {
+				info.TotalTokens += m.PromptTokens + m.OutputTokens
+			}
 		}
 
 		// Extract edited files from textEditGroup response parts
@@ -207,36 +388,136 @@ func parseCopilotSession(jsonPath string, repoRoot string) (*SessionInfo, error)
 			if part.URI == nil || part.URI.Path == "" {
 				continue
 			}
-			absPath := part.URI.Path
-			relPath := strings.TrimPrefix(absPath, repoRoot+"/")
-			if relPath != absPath {
+			if relPath, ok := repoRelPath(part.URI.Path, repoRoot); ok {
 				info.FilesWritten[relPath] = struct{}{}
+				delete(undone, relPath)
 			}
 		}
+
+		// Later keep/undo decisions override earlier edits
+		for _, ev := range req.EditedFileEvents {
+			relPath, ok := repoRelPath(ev.URI.Path, repoRoot)
+			if !ok {
+				continue
+			}
+			if ev.EventKind == copilotEditEventUndo {
+				undone[relPath] = struct{}{}
+			} else {
+				delete(undone, relPath)
+			}
+		}
+	}
+
+	for f := range undone {
+		delete(info.FilesWritten, f)
 	}
 
 	if len(info.FilesWritten) == 0 {
 		return nil, nil
 	}
 
+	// Extract model from selectedModel metadata, falling back to the
+	// last per-request model
+	if session.SelectedModel != nil && session.SelectedModel.Metadata != nil &&
+		session.SelectedModel.Metadata.Family != "" {
+		info.Model = session.SelectedModel.Metadata.Family
+	} else {
+		info.Model = requestModel
+	}
+
 	if firstTimestamp > 0 && lastTimestamp > 0 {
 		info.SessionDurationSec = (lastTimestamp - firstTimestamp) / 1000
 	}
+	if lastTimestamp > 0 {
+		info.LastActivity = time.UnixMilli(lastTimestamp)
+	}
 
 	return info, nil
 }
 
+// parseCopilotEditingSession reads a chatEditingSessions state.json file and
+// returns the files whose edits the user accepted, those whose edits are
+// still pending review (applied on disk, but not kept yet) and those whose
+// edits were rejected.
+func parseCopilotEditingSession(statePath string, repoRoot string) (accepted, pending, rejected map[string]struct{}, model string) {
+	data, err := os.ReadFile(statePath)
+	if err != nil {
+		return nil, nil, nil, ""
+	}
+	var state copilotEditingState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, nil, nil, ""
+	}
+
+	// The recent snapshot reflects the latest decisions; older builds only
+	// record them in the last stop of the linear history.
+	snapshot := state.RecentSnapshot
+	if snapshot == nil {
+		for i := len(state.LinearHistory) - 1; i >= 0 && snapshot == nil; i-- {
+			stops := state.LinearHistory[i].Stops
+			if len(stops) > 0 {
+				snapshot = &stops[len(stops)-1]
+			}
+		}
+	}
+	if snapshot == nil {
+		return nil, nil, nil, ""
+	}
+
+	accepted = make(map[string]struct{})
+	pending = make(map[string]struct{})
+	rejected = make(map[string]struct{})
+	for _, entry := range snapshot.Entries {
+		relPath, ok := repoRelPath(uriToPath(entry.Resource), repoRoot)
+		if !ok {
+			continue
+		}
+		switch entry.State {
+		case copilotEditAccepted:
+			accepted[relPath] = struct{}{}
+		case copilotEditPending:
+			pending[relPath] = struct{}{}
+		case copilotEditRejected:
+			rejected[relPath] = struct{}{}
+		}
+		if entry.TelemetryInfo != nil && entry.TelemetryInfo.ModelID != "" {
+			model = entry.TelemetryInfo.ModelID
+		}
+	}
+	return accepted, pending, rejected, model
+}
+
+// repoRelPath returns absPath relative to repoRoot, or false if it lies
+// outside the repo.
+func repoRelPath(absPath, repoRoot string) (string, bool) {
+	relPath := strings.TrimPrefix(absPath, repoRoot+"/")
+	if relPath == absPath || relPath == "" {
+		return "", false
+	}
+	return relPath, true
+}
+
 // detectCopilot finds recent Copilot Agent sessions for the repo
-// and merges their file sets.
-func detectCopilot(repoRoot string, maxAge time.Duration) (*SessionInfo, error) {
-	workspaceDir := findCopilotWorkspace(repoRoot)
-	if workspaceDir == "" {
-		return nil, nil
+// across every workspace that covers it and merges their file sets.
+func detectCopilot(repoRoot string, roots []string, maxAge time.Duration) (*SessionInfo, error) {
+	var merged *SessionInfo
+	for _, ws := range findWorkspaces(repoRoot, roots) {
+		merged = mergeSessions(merged, detectCopilotWorkspace(ws.Dir, repoRoot, maxAge))
 	}
+	return merged, nil
+}
 
+// detectCopilotWorkspace reads the Copilot sessions of one workspace
+// storage directory. Chat sessions are reconciled with the editing
+// session of the same ID so rejected edits are not counted.
+func detectCopilotWorkspace(workspaceDir string, repoRoot string, maxAge time.Duration) *SessionInfo {
 	sessions, err := findCopilotSessions(workspaceDir, maxAge)
-	if err != nil || len(sessions) == 0 {
-		return nil, nil
+	if err != nil {
+		return nil
+	}
+	editingSessions := findCopilotEditingSessions(workspaceDir, maxAge)
+	if len(sessions) == 0 && len(editingSessions) == 0 {
+		return nil
 	}
 
 	merged := &SessionInfo{
@@ -249,19 +530,56 @@ func detectCopilot(repoRoot string, maxAge time.Duration) (*SessionInfo, error)
 		if err != nil || session == nil {
 			continue
 		}
+		sessionID := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(path), ".jsonl"), ".json")
+		if statePath, ok := editingSessions[sessionID]; ok {
+			_, _, rejected, _ := parseCopilotEditingSession(statePath, repoRoot)
+			for f := range rejected {
+				delete(session.FilesWritten, f)
+			}
+		}
 		for f := range session.FilesWritten {
 			merged.FilesWritten[f] = struct{}{}
 		}
 		if session.Model != "" {
 			merged.Model = session.Model
 		}
+		merged.TotalTokens += session.TotalTokens
 		if session.SessionDurationSec > merged.SessionDurationSec {
 			merged.SessionDurationSec = session.SessionDurationSec
 		}
+		if session.LastActivity.After(merged.LastActivity) {
+			merged.LastActivity = session.LastActivity
+		}
+	}
+
+	// Editing sessions also cover agent edits whose chat log was not kept
+	ids := make([]string, 0, len(editingSessions))
+	for id := range editingSessions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		statePath := editingSessions[id]
+		// Pending edits are on disk, so a commit holding them carries AI
+		// code, but only accepted ones count as reviewed and kept
+		accepted, pending, rejected, model := parseCopilotEditingSession(statePath, repoRoot)
+		for _, files := range []map[string]struct{}{accepted, pending} {
+			for f := range files {
+				merged.FilesWritten[f] = struct{}{}
+			}
+		}
+		merged.EditsAccepted += len(accepted)
+		merged.EditsRejected += len(rejected)
+		if merged.Model == "" && model != "" {
+			merged.Model = model
+		}
+		if st, err := os.Stat(statePath); err == nil && st.ModTime().After(merged.LastActivity) {
+			merged.LastActivity = st.ModTime()
+		}
 	}
 
 	if len(merged.FilesWritten) == 0 {
-		return nil, nil
+		return nil
 	}
-	return merged, nil
+	return merged
 }
@@ -31,6 +31,8 @@ func TestUriToPath(t *testing.T) {
 		{"file:///home/jose/projects/tempo", "/home/jose/projects/tempo"},
 		{"/Users/jose/projects/tempo", "/Users/jose/projects/tempo"},
 		{"file:///Users/jose/projects/path%20with%20spaces", "/Users/jose/projects/path with spaces"},
+		{"vscode-remote://ssh-remote%2Bdevbox/home/jose/projects/tempo", "/home/jose/projects/tempo"},
+		{"vscode-remote://dev-container%2B7b22686f73745061746822/workspaces/tempo", "/workspaces/tempo"},
 	}
 	for _, tt := range tests {
 		got := uriToPath(tt.uri)
@@ -51,7 +53,7 @@ func TestFindCopilotWorkspace(t *testing.T) {
 	if err := os.MkdirAll(wsDir, 0755); err != nil {
 		t.Fatal(err)
 	}
-	wsJSON := copilotWorkspace{Folder: "file://" + repoRoot}
+	wsJSON := workspaceJSON{Folder: "file://" + repoRoot}
 	data, err := json.Marshal(wsJSON)
 	if err != nil {
 		t.Fatal(err)
@@ -65,7 +67,7 @@ func TestFindCopilotWorkspace(t *testing.T) {
 	if err := os.MkdirAll(otherDir, 0755); err != nil {
 		t.Fatal(err)
 	}
-	otherJSON := copilotWorkspace{Folder: "file:///Users/jose/projects/other"}
+	otherJSON := workspaceJSON{Folder: "file:///Users/jose/projects/other"}
 	otherData, err := json.Marshal(otherJSON)
 	if err != nil {
 		t.Fatal(err)
@@ -74,9 +76,37 @@ func TestFindCopilotWorkspace(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	got := findCopilotWorkspace(repoRoot)
-	if got != wsDir {
-		t.Errorf("got %q, want %q", got, wsDir)
+	got := findWorkspaces(repoRoot, vscodeBaseDirs())
+	if len(got) != 1 || got[0].Dir != wsDir {
+		t.Fatalf("got %+v, want %q", got, wsDir)
+	}
+}
+
+func TestFindCopilotWorkspace_VSCodeServer(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("VS Code Server storage is only searched on Linux")
+	}
+	homeDir := t.TempDir()
+	t.Setenv("HOME", homeDir)
+
+	repoRoot := "/home/jose/projects/myapp"
+
+	wsDir := filepath.Join(homeDir, ".vscode-server", "data", "User", "workspaceStorage", "abc123")
+	if err := os.MkdirAll(wsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	wsJSON := workspaceJSON{Folder: "vscode-remote://ssh-remote%2Bdevbox" + repoRoot}
+	data, err := json.Marshal(wsJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(wsDir, "workspace.json"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got := findWorkspaces(repoRoot, vscodeBaseDirs())
+	if len(got) != 1 || got[0].Dir != wsDir {
+		t.Fatalf("got %+v, want %q", got, wsDir)
 	}
 }
 
@@ -84,9 +114,9 @@ func TestFindCopilotWorkspace_NotFound(t *testing.T) {
 	homeDir := t.TempDir()
 	t.Setenv("HOME", homeDir)
 
-	got := findCopilotWorkspace("/some/repo")
-	if got != "" {
-		t.Errorf("expected empty string, got %q", got)
+	got := findWorkspaces("/some/repo", vscodeBaseDirs())
+	if len(got) != 0 {
+		t.Errorf("expected no workspaces, got %+v", got)
 	}
 }
 
@@ -327,7 +357,7 @@ func TestDetectCopilot_Integration(t *testing.T) {
 	}
 
 	// Write workspace.json
-	wsJSON := copilotWorkspace{Folder: "file://" + repoRoot}
+	wsJSON := workspaceJSON{Folder: "file://" + repoRoot}
 	data, err := json.Marshal(wsJSON)
 	if err != nil {
 		t.Fatal(err)
@@ -341,7 +371,7 @@ func TestDetectCopilot_Integration(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	info, err := detectCopilot(repoRoot, 72*time.Hour)
+	info, err := detectCopilot(repoRoot, vscodeBaseDirs(), 72*time.Hour)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -363,7 +393,7 @@ func TestDetectCopilot_NoWorkspace(t *testing.T) {
 	homeDir := t.TempDir()
 	t.Setenv("HOME", homeDir)
 
-	info, err := detectCopilot("/some/repo", 72*time.Hour)
+	info, err := detectCopilot("/some/repo", vscodeBaseDirs(), 72*time.Hour)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -384,7 +414,7 @@ func TestDetectCopilot_MergesSessions(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	wsJSON := copilotWorkspace{Folder: "file://" + repoRoot}
+	wsJSON := workspaceJSON{Folder: "file://" + repoRoot}
 	data, err := json.Marshal(wsJSON)
 	if err != nil {
 		t.Fatal(err)
@@ -429,7 +459,7 @@ func TestDetectCopilot_MergesSessions(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	info, err := detectCopilot(repoRoot, 72*time.Hour)
+	info, err := detectCopilot(repoRoot, vscodeBaseDirs(), 72*time.Hour)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -452,3 +482,216 @@ func writeCopilotTestJSON(t *testing.T, content string) string {
 	}
 	return tmpFile
 }
+
+func TestParseCopilotSession_JSONLLog(t *testing.T) {
+	content := `{"kind":0,"v":{"version":3,"sessionId":"s1","requests":[]}}
+{"kind":2,"k":["requests"],"v":[{"timestamp":1707800000000,"modelId":"copilot/auto","response":[]}]}
+{"kind":1,"k":["requests",0,"response"],"v":[{"kind":"textEditGroup","uri":{"path":"/Users/jose/myapp/a.go"}}]}
+{"kind":1,"k":["requests",0,"result"],"v":{"metadata":{"resolvedModel":"claude-sonnet-4","promptTokens":1200,"outputTokens":300}}}
+not valid json
+{"kind":2,"k":["requests"],"v":[{"timestamp":1707800030000,"modelId":"copilot/auto","response":[{"kind":"textEditGroup","uri":{"path":"/Users/jose/myapp/b.go"}}],"result":{"usage":{"promptTokens":100,"completionTokens":50}}}]}`
+
+	path := filepath.Join(t.TempDir(), "s1.jsonl")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := parseCopilotSession(path, "/Users/jose/myapp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info == nil {
+		t.Fatal("expected non-nil info")
+	}
+
+	wantFiles := []string{"a.go", "b.go"}
+	gotFiles := sortedKeys(info.FilesWritten)
+	if !equal(gotFiles, wantFiles) {
+		t.Errorf("files: got %v, want %v", gotFiles, wantFiles)
+	}
+	// Tokens: (1200+300) + (100+50) = 1650
+	if info.TotalTokens != 1650 {
+		t.Errorf("tokens: got %d, want 1650", info.TotalTokens)
+	}
+	if info.Model != "copilot/auto" {
+		t.Errorf("model: got %q, want last per-request model %q", info.Model, "copilot/auto")
+	}
+	if info.SessionDurationSec != 30 {
+		t.Errorf("duration: got %d, want 30", info.SessionDurationSec)
+	}
+}
+
+func TestReplayCopilotLog_Delete(t *testing.T) {
+	content := `{"kind":0,"v":{"requests":[{"modelId":"a"},{"modelId":"b"}]}}
+{"kind":3,"k":["requests",0]}`
+
+	data, err := replayCopilotLog([]byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var session copilotSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		t.Fatal(err)
+	}
+	if len(session.Requests) != 1 || session.Requests[0].ModelID != "b" {
+		t.Errorf("expected only request b after delete, got %+v", session.Requests)
+	}
+}
+
+func TestParseCopilotSession_UndoneEdits(t *testing.T) {
+	content := `{
+		"version": 3,
+		"requests": [
+			{
+				"timestamp": 1707800000000,
+				"modelId": "copilot/gpt-4o",
+				"response": [
+					{"kind": "textEditGroup", "uri": {"path": "/Users/jose/myapp/kept.go"}},
+					{"kind": "textEditGroup", "uri": {"path": "/Users/jose/myapp/undone.go"}}
+				]
+			},
+			{
+				"timestamp": 1707800010000,
+				"modelId": "copilot/gpt-4o",
+				"editedFileEvents": [
+					{"uri": {"path": "/Users/jose/myapp/kept.go"}, "eventKind": 1},
+					{"uri": {"path": "/Users/jose/myapp/undone.go"}, "eventKind": 2}
+				],
+				"response": []
+			}
+		]
+	}`
+	path := writeCopilotTestJSON(t, content)
+	info, err := parseCopilotSession(path, "/Users/jose/myapp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info == nil {
+		t.Fatal("expected non-nil info")
+	}
+
+	wantFiles := []string{"kept.go"}
+	gotFiles := sortedKeys(info.FilesWritten)
+	if !equal(gotFiles, wantFiles) {
+		t.Errorf("files: got %v, want %v", gotFiles, wantFiles)
+	}
+}
+
+const testCopilotEditingState = `{
+  "version": 2,
+  "recentSnapshot": {
+    "entries": [
+      {"resource": "file:///Users/jose/myapp/accepted.go", "state": 1, "telemetryInfo": {"requestId": "r1", "modelId": "copilot/claude-sonnet-4"}},
+      {"resource": "file:///Users/jose/myapp/pending.go", "state": 0},
+      {"resource": "file:///Users/jose/myapp/rejected.go", "state": 2},
+      {"resource": "file:///Users/jose/other/outside.go", "state": 1}
+    ]
+  }
+}`
+
+func TestParseCopilotEditingSession(t *testing.T) {
+	path := writeCopilotTestJSON(t, testCopilotEditingState)
+	accepted, pending, rejected, model := parseCopilotEditingSession(path, "/Users/jose/myapp")
+
+	if got := sortedKeys(accepted); !equal(got, []string{"accepted.go"}) {
+		t.Errorf("accepted: got %v", got)
+	}
+	if got := sortedKeys(pending); !equal(got, []string{"pending.go"}) {
+		t.Errorf("pending: got %v", got)
+	}
+	if got := sortedKeys(rejected); !equal(got, []string{"rejected.go"}) {
+		t.Errorf("rejected: got %v", got)
+	}
+	if model != "copilot/claude-sonnet-4" {
+		t.Errorf("model: got %q, want %q", model, "copilot/claude-sonnet-4")
+	}
+}
+
+func TestParseCopilotEditingSession_LinearHistory(t *testing.T) {
+	content := `{
+		"linearHistory": [
+			{"stops": [
+				{"entries": [{"resource": "file:///Users/jose/myapp/old.go", "state": 0}]},
+				{"entries": [{"resource": "file:///Users/jose/myapp/old.go", "state": 2},
+				             {"resource": "file:///Users/jose/myapp/new.go", "state": 1}]}
+			]}
+		]
+	}`
+	path := writeCopilotTestJSON(t, content)
+	accepted, _, rejected, _ := parseCopilotEditingSession(path, "/Users/jose/myapp")
+
+	if got := sortedKeys(accepted); !equal(got, []string{"new.go"}) {
+		t.Errorf("accepted: got %v", got)
+	}
+	if got := sortedKeys(rejected); !equal(got, []string{"old.go"}) {
+		t.Errorf("rejected: got %v", got)
+	}
+}
+
+func TestDetectCopilot_EditingSessions(t *testing.T) {
+	homeDir := t.TempDir()
+	t.Setenv("HOME", homeDir)
+
+	repoRoot := "/Users/jose/myapp"
+
+	wsDir := filepath.Join(testVSCodeWorkspaceStorage(homeDir), "abc123")
+	chatDir := filepath.Join(wsDir, "chatSessions")
+	editDir := filepath.Join(wsDir, "chatEditingSessions", "sess-1")
+	for _, d := range []string{chatDir, editDir} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wsJSON := workspaceJSON{Folder: "file://" + repoRoot}
+	data, err := json.Marshal(wsJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(wsDir, "workspace.json"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Chat session edits rejected.go; its editing session records the rejection
+	session := `{
+		"version": 3,
+		"requests": [{
+			"timestamp": 1707800000000,
+			"modelId": "copilot/auto",
+			"result": {"usage": {"promptTokens": 400, "completionTokens": 100}},
+			"response": [
+				{"kind": "textEditGroup", "uri": {"path": "/Users/jose/myapp/rejected.go"}},
+				{"kind": "textEditGroup", "uri": {"path": "/Users/jose/myapp/accepted.go"}}
+			]
+		}]
+	}`
+	if err := os.WriteFile(filepath.Join(chatDir, "sess-1.json"), []byte(session), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(editDir, "state.json"), []byte(testCopilotEditingState), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := detectCopilot(repoRoot, vscodeBaseDirs(), 72*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info == nil {
+		t.Fatal("expected non-nil info")
+	}
+
+	wantFiles := []string{"accepted.go", "pending.go"}
+	gotFiles := sortedKeys(info.FilesWritten)
+	if !equal(gotFiles, wantFiles) {
+		t.Errorf("files: got %v, want %v", gotFiles, wantFiles)
+	}
+	if info.TotalTokens != 500 {
+		t.Errorf("tokens: got %d, want 500", info.TotalTokens)
+	}
+	if info.Model != "copilot/auto" {
+		t.Errorf("model: got %q, want %q", info.Model, "copilot/auto")
+	}
+	if info.EditsAccepted != 1 || info.EditsRejected != 1 {
+		t.Errorf("edits: got %d accepted, %d rejected; want the pending edit in neither", info.EditsAccepted, info.EditsRejected)
+	}
+}
@@ -0,0 +1,87 @@
+package detector
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Correction is a developer's manual fix to a commit's attribution, made
+// with tempo-cli annotate. It either attributes the commit to Tool (a
+// missed detection), removes Tool's detections (NotAI with a Tool, a false
+// positive), or marks the whole commit as not AI-assisted (NotAI alone).
+// A Note may accompany any of these, or stand alone.
+type Correction struct {
+	Tool  Tool     `json:"tool,omitempty"`
+	Files []string `json:"files,omitempty"`
+	NotAI bool     `json:"not_ai,omitempty"`
+	Note  string   `json:"note,omitempty"`
+
+	// Audit fields: who made the correction (git user.email) and when
+	// (RFC 3339, UTC).
+	CorrectedBy string `json:"corrected_by"`
+	CorrectedAt string `json:"corrected_at"`
+}
+
+// Validate reports whether the correction says anything and names a known
+// tool.
+func (c *Correction) Validate() error {
+	if c.Tool == "" && !c.NotAI && c.Note == "" {
+		return fmt.Errorf("nothing to record: give a tool, not-ai or a note")
+	}
+	if c.Tool != "" && !slices.Contains(knownTools, c.Tool) {
+		names := make([]string, len(knownTools))
+		for i, t := range knownTools {
+			names[i] = string(t)
+		}
+		return fmt.Errorf("unknown tool %q (want one of %s)", c.Tool, strings.Join(names, ", "))
+	}
+	if len(c.Files) > 0 && c.Tool == "" {
+		return fmt.Errorf("files need a tool")
+	}
+	if len(c.Files) > 0 && c.NotAI {
+		return fmt.Errorf("files cannot be given with not-ai")
+	}
+	return nil
+}
+
+// Apply applies c to attr and records it in attr.Corrections. A manual
+// detection for Tool replaces the tool's automated detections; detections
+// for other tools are kept. filesCommitted is the number of files in the
+// commit.
+func (c *Correction) Apply(attr *Attribution, filesCommitted int) {
+	switch {
+	case c.NotAI && c.Tool == "":
+		attr.Detections = []Detection{}
+	case c.NotAI:
+		attr.Detections = slices.DeleteFunc(attr.Detections, func(d Detection) bool {
+			return d.Tool == c.Tool
+		})
+	case c.Tool != "":
+		var model string
+		for _, d := range attr.Detections {
+			if d.Tool == c.Tool && d.Model != "" {
+				model = d.Model
+			}
+		}
+		attr.Detections = slices.DeleteFunc(attr.Detections, func(d Detection) bool {
+			return d.Tool == c.Tool
+		})
+		attr.Detections = append(attr.Detections, c.Detection(filesCommitted, model))
+	}
+	attr.Corrections = append(attr.Corrections, *c)
+}
+
+// Detection returns the manual detection c records for its tool.
+func (c *Correction) Detection(filesCommitted int, model string) Detection {
+	return Detection{
+		Tool:           c.Tool,
+		Confidence:     ConfidenceHigh,
+		Method:         MethodManual,
+		FilesMatched:   c.Files,
+		FilesCommitted: filesCommitted,
+		AIFiles:        len(c.Files),
+		Model:          model,
+		Score:          1,
+	}
+}
@@ -0,0 +1,83 @@
+package detector
+
+import (
+	"testing"
+)
+
+func correctionFixture() *Attribution {
+	return &Attribution{
+		CommitSHA: "abc123",
+		Detections: []Detection{
+			{Tool: ToolClaudeCode, Confidence: ConfidenceHigh, Method: MethodFileMatch, AIFiles: 1, FilesCommitted: 3, Model: "claude-opus-4-6"},
+			{Tool: ToolCursor, Confidence: ConfidenceLow, Method: MethodProcess, FilesCommitted: 3},
+		},
+	}
+}
+
+func TestCorrectionApply_Tool(t *testing.T) {
+	attr := correctionFixture()
+	c := &Correction{Tool: ToolClaudeCode, Files: []string{"a.go", "b.go"}, CorrectedBy: "dev@example.com", CorrectedAt: "2026-02-12T17:00:00Z"}
+	c.Apply(attr, 3)
+
+	if len(attr.Detections) != 2 {
+		t.Fatalf("got %d detections, want 2: %+v", len(attr.Detections), attr.Detections)
+	}
+	if attr.Detections[0].Tool != ToolCursor {
+		t.Errorf("other tools should be kept, got %+v", attr.Detections[0])
+	}
+	d := attr.Detections[1]
+	if d.Tool != ToolClaudeCode || d.Method != MethodManual || d.Confidence != ConfidenceHigh {
+		t.Errorf("got %s/%s/%s, want claude-code/manual/high", d.Tool, d.Method, d.Confidence)
+	}
+	if d.AIFiles != 2 || d.FilesCommitted != 3 {
+		t.Errorf("files: got %d/%d, want 2/3", d.AIFiles, d.FilesCommitted)
+	}
+	if d.Model != "claude-opus-4-6" {
+		t.Errorf("model should carry over from the replaced detection, got %q", d.Model)
+	}
+	if len(attr.Corrections) != 1 || attr.Corrections[0].CorrectedBy != "dev@example.com" {
+		t.Errorf("corrections: got %+v", attr.Corrections)
+	}
+}
+
+func TestCorrectionApply_NotAI(t *testing.T) {
+	attr := correctionFixture()
+	(&Correction{Tool: ToolCursor, NotAI: true}).Apply(attr, 3)
+	if len(attr.Detections) != 1 || attr.Detections[0].Tool != ToolClaudeCode {
+		t.Errorf("not-ai with a tool should remove only that tool, got %+v", attr.Detections)
+	}
+
+	attr = correctionFixture()
+	(&Correction{NotAI: true, Note: "hand written"}).Apply(attr, 3)
+	if attr.Detections == nil || len(attr.Detections) != 0 {
+		t.Errorf("not-ai should leave an empty detection list, got %#v", attr.Detections)
+	}
+
+	// Corrections accumulate
+	(&Correction{Note: "checked again"}).Apply(attr, 3)
+	if len(attr.Corrections) != 2 {
+		t.Errorf("got %d corrections, want 2", len(attr.Corrections))
+	}
+}
+
+func TestCorrectionValidate(t *testing.T) {
+	tests := []struct {
+		c       Correction
+		wantErr bool
+	}{
+		{Correction{Tool: ToolCursor}, false},
+		{Correction{NotAI: true}, false},
+		{Correction{Note: "context"}, false},
+		{Correction{}, true},
+		{Correction{Files: []string{"a.go"}}, true},
+		{Correction{Tool: ToolCursor, NotAI: true, Files: []string{"a.go"}}, true},
+		{Correction{Tool: ToolWindsurf, NotAI: true}, false},
+		{Correction{Tool: "claude"}, true},
+		{Correction{Tool: "curosr", NotAI: true}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.c.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%+v): got %v, wantErr %v", tt.c, err, tt.wantErr)
+		}
+	}
+}
@@ -1,15 +1,14 @@
 package detector
 
 import (
+	"bytes"
 	"encoding/json"
-	"fmt"
-	"net/url"
+	"errors"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"runtime"
-	"strings"
 	"time"
+
+	"github.com/usetempo/tempo-cli/internal/sqlite"
 )
 
 // Cursor Agent session detection via SQLite state.vscdb databases.
@@ -24,7 +23,9 @@ import (
 // File edits appear in bubble toolFormerData with names: edit_file, search_replace, create_file, write_file.
 // File paths are in params.relativeWorkspacePath (already relative to workspace root).
 //
-// We shell out to sqlite3 CLI rather than embedding a Go SQLite driver, to keep the binary lean.
+// The databases are read with the built-in read-only SQLite reader (internal/sqlite), so
+// detection works without the sqlite3 CLI and with CGO_ENABLED=0. Each database is opened
+// once per detection and keys are looked up through the tables' key indexes.
 
 // --- JSON types for Cursor session data ---
 
@@ -87,136 +88,54 @@ var cursorWriteTools = map[string]bool{
 	"write":          true,
 }
 
-// sqliteQuery runs a SQL query against a SQLite database using the sqlite3 CLI.
-// Returns the parsed JSON output as a slice of maps. Returns nil if sqlite3 is
-// not available or if the query fails.
-func sqliteQuery(dbPath, query string) ([]map[string]json.RawMessage, error) {
-	sqlite3Path, err := exec.LookPath("sqlite3")
+// cursorKVTable opens a state.vscdb key/value table (ItemTable or cursorDiskKV).
+// The caller must close the returned database.
+func cursorKVTable(dbPath, table string) (*sqlite.DB, *sqlite.Table, error) {
+	db, err := sqlite.Open(dbPath)
 	if err != nil {
-		return nil, fmt.Errorf("sqlite3 not found: %w", err)
+		return nil, nil, err
 	}
-
-	cmd := exec.Command(sqlite3Path, "-json", dbPath, query)
-	out, err := cmd.Output()
+	t, err := db.Table(table)
 	if err != nil {
-		return nil, fmt.Errorf("sqlite3 query failed: %w", err)
-	}
-
-	trimmed := strings.TrimSpace(string(out))
-	if trimmed == "" || trimmed == "[]" {
-		return nil, nil
+		db.Close()
+		return nil, nil, err
 	}
-
-	var rows []map[string]json.RawMessage
-	if err := json.Unmarshal([]byte(trimmed), &rows); err != nil {
-		return nil, fmt.Errorf("parsing sqlite3 output: %w", err)
-	}
-	return rows, nil
+	return db, t, nil
 }
 
-// sqliteQueryValue runs a query that returns a single "value" column and
-// returns the raw string values.
-func sqliteQueryValues(dbPath, query string) ([]string, error) {
-	rows, err := sqliteQuery(dbPath, query)
-	if err != nil || len(rows) == 0 {
-		return nil, err
-	}
-
-	var values []string
-	for _, row := range rows {
-		raw, ok := row["value"]
-		if !ok {
-			continue
-		}
-		// The value may be a JSON string or raw text; unquote if needed.
-		var s string
-		if err := json.Unmarshal(raw, &s); err != nil {
-			s = strings.Trim(string(raw), "\"")
+// cursorKVGet returns the value stored under key, or nil if it is absent.
+func cursorKVGet(t *sqlite.Table, key string) ([]byte, error) {
+	row, err := t.Lookup("key", key)
+	if err != nil {
+		if errors.Is(err, sqlite.ErrNotFound) {
+			return nil, nil
 		}
-		values = append(values, s)
+		return nil, err
 	}
-	return values, nil
+	return cursorKVValue(row), nil
 }
 
-// cursorBaseDirs returns the Cursor workspace storage base directories
-// for the current OS.
-func cursorBaseDirs() []string {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
+// cursorKVValue returns a key/value row's value column as bytes; values may
+// be stored as TEXT or BLOB.
+func cursorKVValue(row sqlite.Row) []byte {
+	if len(row.Values) < 2 {
 		return nil
 	}
-
-	switch runtime.GOOS {
-	case "darwin":
-		return []string{
-			filepath.Join(homeDir, "Library", "Application Support", "Cursor", "User", "workspaceStorage"),
-		}
-	case "linux":
-		return []string{
-			filepath.Join(homeDir, ".config", "Cursor", "User", "workspaceStorage"),
-		}
+	switch v := row.Values[1].(type) {
+	case string:
+		return []byte(v)
+	case []byte:
+		return v
 	}
 	return nil
 }
 
-// cursorGlobalDBPath returns the path to the global Cursor state.vscdb.
-func cursorGlobalDBPath() string {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return ""
-	}
-
-	switch runtime.GOOS {
-	case "darwin":
-		return filepath.Join(homeDir, "Library", "Application Support", "Cursor", "User", "globalStorage", "state.vscdb")
-	case "linux":
-		return filepath.Join(homeDir, ".config", "Cursor", "User", "globalStorage", "state.vscdb")
-	}
-	return ""
-}
-
-// findCursorWorkspace finds the Cursor workspace storage directory
-// whose workspace.json maps to the given repo root.
-func findCursorWorkspace(repoRoot string) string {
-	for _, baseDir := range cursorBaseDirs() {
-		entries, err := os.ReadDir(baseDir)
-		if err != nil {
-			continue
-		}
-		for _, entry := range entries {
-			if !entry.IsDir() {
-				continue
-			}
-			wsPath := filepath.Join(baseDir, entry.Name(), "workspace.json")
-			data, err := os.ReadFile(wsPath)
-			if err != nil {
-				continue
-			}
-			var ws struct {
-				Folder string `json:"folder"`
-			}
-			if err := json.Unmarshal(data, &ws); err != nil {
-				continue
-			}
-			folder := cursorURIToPath(ws.Folder)
-			if folder == repoRoot {
-				return filepath.Join(baseDir, entry.Name())
-			}
-		}
-	}
-	return ""
-}
-
-// cursorURIToPath converts a file:// URI to a local path.
-func cursorURIToPath(uri string) string {
-	if !strings.HasPrefix(uri, "file://") {
-		return uri
-	}
-	u, err := url.Parse(uri)
-	if err != nil {
-		return strings.TrimPrefix(uri, "file://")
-	}
-	return u.Path
+// cursorGlobalDBPath returns the path to the global state.vscdb that sits
+// alongside the given workspace storage directory
+// (.../User/workspaceStorage/{hash} → .../User/globalStorage/state.vscdb).
+func cursorGlobalDBPath(workspaceDir string) string {
+	userDir := filepath.Dir(filepath.Dir(workspaceDir))
+	return filepath.Join(userDir, "globalStorage", "state.vscdb")
 }
 
 // findCursorComposers reads the workspace state.vscdb and returns recent
@@ -226,18 +145,23 @@ func findCursorComposers(workspaceDBPath string, maxAge time.Duration) ([]cursor
 		return nil, nil
 	}
 
-	values, err := sqliteQueryValues(workspaceDBPath,
-		`SELECT value FROM ItemTable WHERE key = 'composer.composerData'`)
-	if err != nil || len(values) == 0 {
+	db, table, err := cursorKVTable(workspaceDBPath, "ItemTable")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	value, err := cursorKVGet(table, "composer.composerData")
+	if err != nil || value == nil {
 		return nil, err
 	}
 
 	// Try modern format: {"allComposers": [...]}
 	var index cursorComposerIndex
-	if err := json.Unmarshal([]byte(values[0]), &index); err != nil {
+	if err := json.Unmarshal(value, &index); err != nil {
 		// Try legacy format: direct array [...]
 		var composers []cursorComposerHead
-		if err := json.Unmarshal([]byte(values[0]), &composers); err != nil {
+		if err := json.Unmarshal(value, &composers); err != nil {
 			return nil, nil
 		}
 		index.AllComposers = composers
@@ -253,43 +177,26 @@ func findCursorComposers(workspaceDBPath string, maxAge time.Duration) ([]cursor
 	return recent, nil
 }
 
-// parseCursorBubbles queries the global state.vscdb for file-writing tool calls
-// across the given composer sessions.
-func parseCursorBubbles(globalDBPath string, composerIds []string) (*SessionInfo, error) {
-	if _, err := os.Stat(globalDBPath); err != nil {
-		return nil, nil
-	}
-
-	// Check if cursorDiskKV table exists
-	rows, err := sqliteQuery(globalDBPath,
-		`SELECT name FROM sqlite_master WHERE type='table' AND name='cursorDiskKV'`)
-	if err != nil || len(rows) == 0 {
-		return nil, nil
-	}
-
+// readCursorBubbles collects file-writing tool calls for the given composers
+// from an open cursorDiskKV table. Returns nil if no files were written.
+func readCursorBubbles(table *sqlite.Table, composerIds []string) *SessionInfo {
 	info := &SessionInfo{
 		Tool:         ToolCursor,
 		FilesWritten: make(map[string]struct{}),
 	}
 
 	for _, composerId := range composerIds {
-		// Use range-based prefix search for index efficiency (LIKE causes full table scan)
-		// ';' is the ASCII character after ':', so key < 'bubbleId:xxx;' covers all 'bubbleId:xxx:*' keys
-		query := fmt.Sprintf(
-			`SELECT value FROM cursorDiskKV WHERE key >= 'bubbleId:%s:' AND key < 'bubbleId:%s;' `+
-				`AND (value LIKE '%%"edit_file"%%' OR value LIKE '%%"search_replace"%%' `+
-				`OR value LIKE '%%"create_file"%%' OR value LIKE '%%"write_file"%%')`,
-			composerId, composerId)
-
-		values, err := sqliteQueryValues(globalDBPath, query)
-		if err != nil {
-			continue
-		}
+		// All bubbles of a composer share the "bubbleId:{composerId}:" key prefix
+		err := table.ScanPrefix("key", "bubbleId:"+composerId+":", func(row sqlite.Row) error {
+			val := cursorKVValue(row)
+			// Pre-filter: skip bubbles that don't mention a write tool
+			if !containsWriteTool(val) {
+				return nil
+			}
 
-		for _, val := range values {
 			var bubble cursorBubble
-			if err := json.Unmarshal([]byte(val), &bubble); err != nil {
-				continue
+			if err := json.Unmarshal(val, &bubble); err != nil {
+				return nil
 			}
 
 			// Sum token counts
@@ -298,35 +205,52 @@ func parseCursorBubbles(globalDBPath string, composerIds []string) (*SessionInfo
 			}
 
 			if bubble.ToolFormerData == nil {
-				continue
+				return nil
 			}
 			tf := bubble.ToolFormerData
 
 			// Only count file-writing tools
 			if !cursorWriteTools[tf.Name] {
-				continue
+				return nil
 			}
 
 			// Filter: must be completed and not rejected
 			if tf.Status != "completed" {
-				continue
+				return nil
 			}
 			if tf.UserDecision == "rejected" {
-				continue
+				info.EditsRejected++
+				return nil
+			}
+			if tf.UserDecision == "accepted" {
+				info.EditsAccepted++
 			}
 
 			// Extract file path
-			filePath := extractCursorFilePath(tf)
-			if filePath != "" {
+			if filePath := extractCursorFilePath(tf); filePath != "" {
 				info.FilesWritten[filePath] = struct{}{}
 			}
+			return nil
+		})
+		if err != nil {
+			continue
 		}
 	}
 
 	if len(info.FilesWritten) == 0 {
-		return nil, nil
+		return nil
+	}
+	return info
+}
+
+// containsWriteTool reports whether a raw bubble value names a file-writing tool.
+func containsWriteTool(val []byte) bool {
+	for name := range cursorWriteTools {
+		if bytes.Contains(val, []byte(`"`+name+`"`)) {
+			return true
+		}
 	}
-	return info, nil
+	return false
 }
 
 // extractCursorFilePath extracts the relative file path from a tool call's
@@ -358,17 +282,16 @@ func extractCursorFilePath(tf *cursorToolFormer) string {
 	return ""
 }
 
-// parseCursorComposerModel extracts the model name from a composer's metadata.
-func parseCursorComposerModel(globalDBPath string, composerId string) string {
-	query := fmt.Sprintf(
-		`SELECT value FROM cursorDiskKV WHERE key = 'composerData:%s'`, composerId)
-	values, err := sqliteQueryValues(globalDBPath, query)
-	if err != nil || len(values) == 0 {
+// readCursorComposerModel extracts a composer's model from an open
+// cursorDiskKV table.
+func readCursorComposerModel(table *sqlite.Table, composerId string) string {
+	value, err := cursorKVGet(table, "composerData:"+composerId)
+	if err != nil || value == nil {
 		return ""
 	}
 
 	var data cursorComposerData
-	if err := json.Unmarshal([]byte(values[0]), &data); err != nil {
+	if err := json.Unmarshal(value, &data); err != nil {
 		return ""
 	}
 
@@ -390,28 +313,27 @@ func parseCursorComposerModel(globalDBPath string, composerId string) string {
 }
 
 // detectCursor finds recent Cursor Agent/Composer sessions for the repo
-// and extracts file-level edit information.
-func detectCursor(repoRoot string, maxAge time.Duration) (*SessionInfo, error) {
-	// Check sqlite3 availability
-	if _, err := exec.LookPath("sqlite3"); err != nil {
-		return nil, nil
-	}
-
-	workspaceDir := findCursorWorkspace(repoRoot)
-	if workspaceDir == "" {
-		return nil, nil
-	}
+// across every workspace that covers it and extracts file-level edit
+// information.
+func detectCursor(repoRoot string, roots []string, maxAge time.Duration) (*SessionInfo, error) {
+	var merged *SessionInfo
+	for _, ws := range findWorkspaces(repoRoot, roots) {
+		merged = mergeSessions(merged, detectCursorWorkspace(ws, repoRoot, maxAge))
+	}
+	return merged, nil
+}
 
-	workspaceDBPath := filepath.Join(workspaceDir, "state.vscdb")
+// detectCursorWorkspace reads the composer sessions of one workspace.
+// Paths are recorded relative to the workspace folders, so they are
+// rebased onto the repo and files outside it are dropped.
+func detectCursorWorkspace(ws workspaceMatch, repoRoot string, maxAge time.Duration) *SessionInfo {
+	workspaceDBPath := filepath.Join(ws.Dir, "state.vscdb")
 	composers, err := findCursorComposers(workspaceDBPath, maxAge)
 	if err != nil || len(composers) == 0 {
-		return nil, nil
+		return nil
 	}
 
-	globalDBPath := cursorGlobalDBPath()
-	if globalDBPath == "" {
-		return nil, nil
-	}
+	globalDBPath := cursorGlobalDBPath(ws.Dir)
 
 	var composerIds []string
 	var latestComposerId string
@@ -424,14 +346,33 @@ func detectCursor(repoRoot string, maxAge time.Duration) (*SessionInfo, error) {
 		}
 	}
 
-	info, err := parseCursorBubbles(globalDBPath, composerIds)
-	if err != nil || info == nil {
-		return nil, nil
+	// Read bubbles and the model from the global DB in a single open; each
+	// composer's bubbles are one range scan of the key index
+	db, table, err := cursorKVTable(globalDBPath, "cursorDiskKV")
+	if err != nil {
+		return nil
+	}
+	defer db.Close()
+
+	info := readCursorBubbles(table, composerIds)
+	if info == nil {
+		return nil
 	}
 
+	files := make(map[string]struct{}, len(info.FilesWritten))
+	for f := range info.FilesWritten {
+		if relPath, ok := ws.repoPath(f, repoRoot); ok {
+			files[filepath.ToSlash(relPath)] = struct{}{}
+		}
+	}
+	if len(files) == 0 {
+		return nil
+	}
+	info.FilesWritten = files
+
 	// Extract model from the most recent composer
 	if latestComposerId != "" {
-		info.Model = parseCursorComposerModel(globalDBPath, latestComposerId)
+		info.Model = readCursorComposerModel(table, latestComposerId)
 	}
 
 	// Session duration: earliest createdAt to latest lastUpdatedAt
@@ -447,6 +388,9 @@ func detectCursor(repoRoot string, maxAge time.Duration) (*SessionInfo, error) {
 	if earliest > 0 && latest > earliest {
 		info.SessionDurationSec = (latest - earliest) / 1000
 	}
+	if latest > 0 {
+		info.LastActivity = time.UnixMilli(latest)
+	}
 
-	return info, nil
+	return info
 }
@@ -0,0 +1,153 @@
+package detector
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Cursor Tab completion and Cmd-K inline edit detection.
+//
+// Besides agent/composer tool calls, Cursor records every AI generation it
+// applies in the workspace DB:
+//   workspaceStorage/{hash}/state.vscdb
+//     → ItemTable key "aiService.generations" → JSON array of generation records
+//
+// A record looks like:
+//   {"unixMs": 1707800000000, "generationUUID": "...", "type": "cmdk",
+//    "uri": "file:///path/to/repo/src/main.go", "textDescription": "..."}
+//
+// The file may instead be given as {"uri": {"fsPath": ...}} or as
+// "relativeWorkspacePath". Inline generation types are "tab" / "cpp" (Cursor
+// Tab) and "cmdk" (Cmd-K); "composer", "chat" and "apply" records belong to
+// the agent signal and are skipped here.
+
+// cursorGenerationsKey is the ItemTable key holding generation records.
+const cursorGenerationsKey = "aiService.generations"
+
+type cursorGeneration struct {
+	UnixMs                int64           `json:"unixMs"`
+	Type                  string          `json:"type"`
+	URI                   json.RawMessage `json:"uri"`
+	RelativeWorkspacePath string          `json:"relativeWorkspacePath"`
+}
+
+type cursorGenerationURI struct {
+	FsPath string `json:"fsPath"`
+	Path   string `json:"path"`
+}
+
+// cursorInlineTypes are the generation types produced by Tab and Cmd-K.
+var cursorInlineTypes = map[string]bool{
+	"tab":   true,
+	"cpp":   true,
+	"cmdk":  true,
+	"cmd-k": true,
+}
+
+// parseCursorGenerations reads inline generation records from a workspace's
+// state.vscdb and returns the repo files they touched within maxAge.
+func parseCursorGenerations(ws workspaceMatch, repoRoot string, maxAge time.Duration) (*SessionInfo, error) {
+	workspaceDBPath := filepath.Join(ws.Dir, "state.vscdb")
+	if _, err := os.Stat(workspaceDBPath); err != nil {
+		return nil, nil
+	}
+
+	db, table, err := cursorKVTable(workspaceDBPath, "ItemTable")
+	if err != nil {
+		return nil, nil
+	}
+	defer db.Close()
+
+	value, err := cursorKVGet(table, cursorGenerationsKey)
+	if err != nil || value == nil {
+		return nil, err
+	}
+
+	var generations []cursorGeneration
+	if err := json.Unmarshal(value, &generations); err != nil {
+		return nil, nil
+	}
+
+	info := &SessionInfo{
+		Tool:         ToolCursor,
+		FilesWritten: make(map[string]struct{}),
+	}
+
+	cutoff := time.Now().Add(-maxAge).UnixMilli()
+	var earliest, latest int64
+	for _, g := range generations {
+		if !cursorInlineTypes[strings.ToLower(g.Type)] || g.UnixMs <= cutoff {
+			continue
+		}
+		filePath := cursorGenerationPath(g, ws, repoRoot)
+		if filePath == "" {
+			continue
+		}
+		info.FilesWritten[filePath] = struct{}{}
+		if earliest == 0 || g.UnixMs < earliest {
+			earliest = g.UnixMs
+		}
+		if g.UnixMs > latest {
+			latest = g.UnixMs
+		}
+	}
+
+	if len(info.FilesWritten) == 0 {
+		return nil, nil
+	}
+	if latest > earliest {
+		info.SessionDurationSec = (latest - earliest) / 1000
+	}
+	info.LastActivity = time.UnixMilli(latest)
+	return info, nil
+}
+
+// cursorGenerationPath returns the repo-relative file a generation applied
+// to, or "" if it has none or lies outside the repo.
+func cursorGenerationPath(g cursorGeneration, ws workspaceMatch, repoRoot string) string {
+	if g.RelativeWorkspacePath != "" {
+		relPath, _ := ws.repoPath(g.RelativeWorkspacePath, repoRoot)
+		return filepath.ToSlash(relPath)
+	}
+	if len(g.URI) == 0 {
+		return ""
+	}
+
+	var absPath string
+	var s string
+	if err := json.Unmarshal(g.URI, &s); err == nil {
+		absPath = uriToPath(s)
+	} else {
+		var u cursorGenerationURI
+		if err := json.Unmarshal(g.URI, &u); err != nil {
+			return ""
+		}
+		absPath = u.FsPath
+		if absPath == "" {
+			absPath = u.Path
+		}
+	}
+
+	if absPath == "" {
+		return ""
+	}
+	relPath, _ := ws.repoPath(absPath, repoRoot)
+	return filepath.ToSlash(relPath)
+}
+
+// detectCursorInline finds recent Tab completions and Cmd-K edits for the
+// repo across every workspace that covers it.
+func detectCursorInline(repoRoot string, roots []string, maxAge time.Duration) (*SessionInfo, error) {
+	var merged *SessionInfo
+	for _, ws := range findWorkspaces(repoRoot, roots) {
+		info, err := parseCursorGenerations(ws, repoRoot, maxAge)
+		if err != nil {
+			continue
+		}
+		merged = mergeSessions(merged, info)
+	}
+	return merged, nil
+}
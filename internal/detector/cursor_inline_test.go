@@ -0,0 +1,111 @@
+package detector
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeCursorGenerations(t *testing.T, dbPath string, generations []map[string]any) {
+	t.Helper()
+	data, err := json.Marshal(generations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	createTestDB(t, dbPath, []string{
+		`CREATE TABLE ItemTable (key TEXT UNIQUE ON CONFLICT REPLACE, value BLOB);`,
+		fmt.Sprintf(`INSERT INTO ItemTable (key, value) VALUES ('aiService.generations', '%s');`,
+			escapeSQLString(string(data))),
+	})
+}
+
+func TestParseCursorGenerations(t *testing.T) {
+	skipIfNoSQLite(t)
+
+	repoRoot := "/Users/jose/projects/myapp"
+	dbPath := filepath.Join(t.TempDir(), "state.vscdb")
+	now := time.Now().UnixMilli()
+	old := time.Now().Add(-5 * 24 * time.Hour).UnixMilli()
+
+	writeCursorGenerations(t, dbPath, []map[string]any{
+		{"unixMs": now - 60000, "type": "cmdk", "uri": "file://" + repoRoot + "/src/main.go"},
+		{"unixMs": now, "type": "tab", "uri": map[string]string{"fsPath": repoRoot + "/src/util.go"}},
+		{"unixMs": now, "type": "cpp", "relativeWorkspacePath": "src/tab.go"},
+		{"unixMs": now, "type": "composer", "uri": "file://" + repoRoot + "/src/agent.go"},
+		{"unixMs": old, "type": "cmdk", "uri": "file://" + repoRoot + "/src/stale.go"},
+		{"unixMs": now, "type": "tab", "uri": "file:///Users/jose/projects/other/x.go"},
+	})
+
+	ws := workspaceMatch{Dir: filepath.Dir(dbPath), Folders: []string{repoRoot}}
+	info, err := parseCursorGenerations(ws, repoRoot, 72*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info == nil {
+		t.Fatal("expected non-nil info")
+	}
+
+	wantFiles := []string{"src/main.go", "src/tab.go", "src/util.go"}
+	gotFiles := sortedKeys(info.FilesWritten)
+	if !equal(gotFiles, wantFiles) {
+		t.Errorf("files: got %v, want %v", gotFiles, wantFiles)
+	}
+	if info.SessionDurationSec != 60 {
+		t.Errorf("duration: got %d, want 60", info.SessionDurationSec)
+	}
+}
+
+func TestParseCursorGenerations_NoKey(t *testing.T) {
+	skipIfNoSQLite(t)
+
+	dbPath := filepath.Join(t.TempDir(), "state.vscdb")
+	createTestDB(t, dbPath, []string{
+		`CREATE TABLE ItemTable (key TEXT UNIQUE ON CONFLICT REPLACE, value BLOB);`,
+	})
+
+	repoRoot := "/Users/jose/projects/myapp"
+	ws := workspaceMatch{Dir: filepath.Dir(dbPath), Folders: []string{repoRoot}}
+	info, err := parseCursorGenerations(ws, repoRoot, 72*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info != nil {
+		t.Errorf("expected nil, got %+v", info)
+	}
+}
+
+func TestDetectCursorInline(t *testing.T) {
+	skipIfNoSQLite(t)
+	homeDir := t.TempDir()
+	t.Setenv("HOME", homeDir)
+
+	repoRoot := "/Users/jose/projects/myapp"
+	wsDir := filepath.Join(testCursorWorkspaceStorage(homeDir), "abc123")
+	if err := os.MkdirAll(wsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	wsJSON, err := json.Marshal(map[string]string{"folder": "file://" + repoRoot})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(wsDir, "workspace.json"), wsJSON, 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeCursorGenerations(t, filepath.Join(wsDir, "state.vscdb"), []map[string]any{
+		{"unixMs": time.Now().UnixMilli(), "type": "cmdk", "uri": "file://" + repoRoot + "/README.md"},
+	})
+
+	info, err := detectCursorInline(repoRoot, cursorBaseDirs(), 72*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info == nil {
+		t.Fatal("expected non-nil info")
+	}
+	if got := sortedKeys(info.FilesWritten); !equal(got, []string{"README.md"}) {
+		t.Errorf("files: got %v", got)
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/usetempo/tempo-cli/internal/sqlite"
 )
 
 // testCursorWorkspaceStorage returns the platform-correct Cursor workspace
@@ -92,9 +94,41 @@ func TestFindCursorWorkspace(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	got := findCursorWorkspace(repoRoot)
-	if got != wsDir {
-		t.Errorf("got %q, want %q", got, wsDir)
+	got := findWorkspaces(repoRoot, cursorBaseDirs())
+	if len(got) != 1 || got[0].Dir != wsDir {
+		t.Fatalf("got %+v, want %q", got, wsDir)
+	}
+}
+
+func TestFindCursorWorkspace_CursorServer(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("Cursor Server storage is only searched on Linux")
+	}
+	homeDir := t.TempDir()
+	t.Setenv("HOME", homeDir)
+
+	repoRoot := "/workspaces/myapp"
+
+	wsDir := filepath.Join(homeDir, ".cursor-server", "data", "User", "workspaceStorage", "abc123")
+	if err := os.MkdirAll(wsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	wsJSON, err := json.Marshal(map[string]string{"folder": "vscode-remote://dev-container%2B7b22/workspaces/myapp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(wsDir, "workspace.json"), wsJSON, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got := findWorkspaces(repoRoot, cursorBaseDirs())
+	if len(got) != 1 || got[0].Dir != wsDir {
+		t.Fatalf("got %+v, want %q", got, wsDir)
+	}
+
+	wantGlobal := filepath.Join(homeDir, ".cursor-server", "data", "User", "globalStorage", "state.vscdb")
+	if g := cursorGlobalDBPath(got[0].Dir); g != wantGlobal {
+		t.Errorf("global db: got %q, want %q", g, wantGlobal)
 	}
 }
 
@@ -102,9 +136,9 @@ func TestFindCursorWorkspace_NotFound(t *testing.T) {
 	homeDir := t.TempDir()
 	t.Setenv("HOME", homeDir)
 
-	got := findCursorWorkspace("/some/repo")
-	if got != "" {
-		t.Errorf("expected empty string, got %q", got)
+	got := findWorkspaces("/some/repo", cursorBaseDirs())
+	if len(got) != 0 {
+		t.Errorf("expected no workspaces, got %+v", got)
 	}
 }
 
@@ -168,7 +202,18 @@ func TestFindCursorComposers_Empty(t *testing.T) {
 	}
 }
 
-func TestParseCursorBubbles_Basic(t *testing.T) {
+// openCursorDiskKV opens the cursorDiskKV table of a test database.
+func openCursorDiskKV(t *testing.T, dbPath string) *sqlite.Table {
+	t.Helper()
+	db, table, err := cursorKVTable(dbPath, "cursorDiskKV")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return table
+}
+
+func TestReadCursorBubbles_Basic(t *testing.T) {
 	skipIfNoSQLite(t)
 
 	dbPath := filepath.Join(t.TempDir(), "state.vscdb")
@@ -231,10 +276,7 @@ func TestParseCursorBubbles_Basic(t *testing.T) {
 			composerId, escapeSQLString(string(dupData))),
 	})
 
-	info, err := parseCursorBubbles(dbPath, []string{composerId})
-	if err != nil {
-		t.Fatal(err)
-	}
+	info := readCursorBubbles(openCursorDiskKV(t, dbPath), []string{composerId})
 	if info == nil {
 		t.Fatal("expected non-nil info")
 	}
@@ -251,7 +293,7 @@ func TestParseCursorBubbles_Basic(t *testing.T) {
 	}
 }
 
-func TestParseCursorBubbles_NoEdits(t *testing.T) {
+func TestReadCursorBubbles_NoEdits(t *testing.T) {
 	skipIfNoSQLite(t)
 
 	dbPath := filepath.Join(t.TempDir(), "state.vscdb")
@@ -276,16 +318,13 @@ func TestParseCursorBubbles_NoEdits(t *testing.T) {
 			composerId, escapeSQLString(string(data))),
 	})
 
-	info, err := parseCursorBubbles(dbPath, []string{composerId})
-	if err != nil {
-		t.Fatal(err)
-	}
+	info := readCursorBubbles(openCursorDiskKV(t, dbPath), []string{composerId})
 	if info != nil {
 		t.Errorf("expected nil for no edits, got %+v", info)
 	}
 }
 
-func TestParseCursorBubbles_MultipleComposers(t *testing.T) {
+func TestReadCursorBubbles_MultipleComposers(t *testing.T) {
 	skipIfNoSQLite(t)
 
 	dbPath := filepath.Join(t.TempDir(), "state.vscdb")
@@ -326,10 +365,7 @@ func TestParseCursorBubbles_MultipleComposers(t *testing.T) {
 			escapeSQLString(string(data2))),
 	})
 
-	info, err := parseCursorBubbles(dbPath, []string{"comp-1", "comp-2"})
-	if err != nil {
-		t.Fatal(err)
-	}
+	info := readCursorBubbles(openCursorDiskKV(t, dbPath), []string{"comp-1", "comp-2"})
 	if info == nil {
 		t.Fatal("expected non-nil info")
 	}
@@ -341,7 +377,7 @@ func TestParseCursorBubbles_MultipleComposers(t *testing.T) {
 	}
 }
 
-func TestParseCursorBubbles_FallbackToRawArgs(t *testing.T) {
+func TestReadCursorBubbles_FallbackToRawArgs(t *testing.T) {
 	skipIfNoSQLite(t)
 
 	dbPath := filepath.Join(t.TempDir(), "state.vscdb")
@@ -368,10 +404,7 @@ func TestParseCursorBubbles_FallbackToRawArgs(t *testing.T) {
 			composerId, escapeSQLString(string(data))),
 	})
 
-	info, err := parseCursorBubbles(dbPath, []string{composerId})
-	if err != nil {
-		t.Fatal(err)
-	}
+	info := readCursorBubbles(openCursorDiskKV(t, dbPath), []string{composerId})
 	if info == nil {
 		t.Fatal("expected non-nil info")
 	}
@@ -383,7 +416,7 @@ func TestParseCursorBubbles_FallbackToRawArgs(t *testing.T) {
 	}
 }
 
-func TestParseCursorBubbles_RejectedEdits(t *testing.T) {
+func TestReadCursorBubbles_RejectedEdits(t *testing.T) {
 	skipIfNoSQLite(t)
 
 	dbPath := filepath.Join(t.TempDir(), "state.vscdb")
@@ -442,10 +475,7 @@ func TestParseCursorBubbles_RejectedEdits(t *testing.T) {
 			composerId, escapeSQLString(string(noDecisionData))),
 	})
 
-	info, err := parseCursorBubbles(dbPath, []string{composerId})
-	if err != nil {
-		t.Fatal(err)
-	}
+	info := readCursorBubbles(openCursorDiskKV(t, dbPath), []string{composerId})
 	if info == nil {
 		t.Fatal("expected non-nil info")
 	}
@@ -457,7 +487,7 @@ func TestParseCursorBubbles_RejectedEdits(t *testing.T) {
 	}
 }
 
-func TestParseCursorComposerModel(t *testing.T) {
+func TestReadCursorComposerModel(t *testing.T) {
 	skipIfNoSQLite(t)
 
 	t.Run("from usageData", func(t *testing.T) {
@@ -472,7 +502,7 @@ func TestParseCursorComposerModel(t *testing.T) {
 				composerId, escapeSQLString(data)),
 		})
 
-		got := parseCursorComposerModel(dbPath, composerId)
+		got := readCursorComposerModel(openCursorDiskKV(t, dbPath), composerId)
 		if got != "claude-4-sonnet-thinking" {
 			t.Errorf("model: got %q, want %q", got, "claude-4-sonnet-thinking")
 		}
@@ -490,7 +520,7 @@ func TestParseCursorComposerModel(t *testing.T) {
 				composerId, escapeSQLString(data)),
 		})
 
-		got := parseCursorComposerModel(dbPath, composerId)
+		got := readCursorComposerModel(openCursorDiskKV(t, dbPath), composerId)
 		if got != "gpt-4o" {
 			t.Errorf("model: got %q, want %q", got, "gpt-4o")
 		}
@@ -508,7 +538,7 @@ func TestParseCursorComposerModel(t *testing.T) {
 				composerId, escapeSQLString(data)),
 		})
 
-		got := parseCursorComposerModel(dbPath, composerId)
+		got := readCursorComposerModel(openCursorDiskKV(t, dbPath), composerId)
 		if got != "" {
 			t.Errorf("model: got %q, want empty string", got)
 		}
@@ -587,7 +617,7 @@ func TestDetectCursor_Integration(t *testing.T) {
 			composerId, escapeSQLString(composerMeta)),
 	})
 
-	info, err := detectCursor(repoRoot, 72*time.Hour)
+	info, err := detectCursor(repoRoot, cursorBaseDirs(), 72*time.Hour)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -615,7 +645,7 @@ func TestDetectCursor_NoWorkspace(t *testing.T) {
 	homeDir := t.TempDir()
 	t.Setenv("HOME", homeDir)
 
-	info, err := detectCursor("/some/repo", 72*time.Hour)
+	info, err := detectCursor("/some/repo", cursorBaseDirs(), 72*time.Hour)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -624,16 +654,60 @@ func TestDetectCursor_NoWorkspace(t *testing.T) {
 	}
 }
 
-func TestDetectCursor_NoSQLite(t *testing.T) {
-	// Test graceful degradation when sqlite3 is not available
+func TestDetectCursor_NoSQLiteCLI(t *testing.T) {
+	skipIfNoSQLite(t)
+	homeDir := t.TempDir()
+	t.Setenv("HOME", homeDir)
+
+	repoRoot := "/Users/jose/projects/myapp"
+
+	wsDir := filepath.Join(testCursorWorkspaceStorage(homeDir), "abc123")
+	if err := os.MkdirAll(wsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	wsJSON, err := json.Marshal(map[string]string{"folder": "file://" + repoRoot})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(wsDir, "workspace.json"), wsJSON, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().UnixMilli()
+	indexData, err := json.Marshal(cursorComposerIndex{
+		AllComposers: []cursorComposerHead{{ComposerID: "c1", LastUpdatedAt: now, CreatedAt: now - 1000}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	createTestDB(t, filepath.Join(wsDir, "state.vscdb"), []string{
+		`CREATE TABLE ItemTable (key TEXT UNIQUE ON CONFLICT REPLACE, value BLOB);`,
+		fmt.Sprintf(`INSERT INTO ItemTable (key, value) VALUES ('composer.composerData', '%s');`,
+			escapeSQLString(string(indexData))),
+	})
+
+	globalDir := testCursorGlobalStorage(homeDir)
+	if err := os.MkdirAll(globalDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	bubble := `{"type":2,"toolFormerData":{"name":"edit_file","status":"completed","params":"{\"relativeWorkspacePath\":\"src/main.go\"}"}}`
+	createTestDB(t, filepath.Join(globalDir, "state.vscdb"), []string{
+		`CREATE TABLE cursorDiskKV (key TEXT UNIQUE ON CONFLICT REPLACE, value BLOB);`,
+		fmt.Sprintf(`INSERT INTO cursorDiskKV (key, value) VALUES ('bubbleId:c1:b1', '%s');`, escapeSQLString(bubble)),
+	})
+
+	// Detection reads the databases natively and must not need sqlite3 on PATH
 	t.Setenv("PATH", "/nonexistent")
 
-	info, err := detectCursor("/some/repo", 72*time.Hour)
+	info, err := detectCursor(repoRoot, cursorBaseDirs(), 72*time.Hour)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if info != nil {
-		t.Errorf("expected nil when sqlite3 not available, got %+v", info)
+	if info == nil {
+		t.Fatal("expected detection without the sqlite3 CLI")
+	}
+	if got := sortedKeys(info.FilesWritten); !equal(got, []string{"src/main.go"}) {
+		t.Errorf("files: got %v", got)
 	}
 }
 
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -14,7 +15,7 @@ const defaultMaxAgeHours = 72
 
 // emptyTreeSHA is the SHA of git's empty tree object, used to diff against
 // when HEAD~1 doesn't exist (e.g. first commit or shallow clone).
-const emptyTreeSHA = "4b825dc642cb6eb9a060e54bf899d69f82cf7186"
+const emptyTreeSHA = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
 
 // sessionMaxAge returns the max session age, defaulting to 72h.
 // Override with TEMPO_SESSION_MAX_AGE env var (value in hours).
@@ -27,9 +28,55 @@ func sessionMaxAge() time.Duration {
 	return defaultMaxAgeHours * time.Hour
 }
 
-// Detect runs the full detection pipeline for the current HEAD commit.
-func Detect(repoRoot string) (*Attribution, error) {
-	committedFiles, err := getCommittedFiles(repoRoot)
+// Options configures a detection run.
+type Options struct {
+	// SessionRoots overrides where each tool's session data is read from.
+	// When nil, roots are resolved from the environment.
+	SessionRoots SessionRoots
+
+	// InHook is set when detection runs from the post-commit hook, so the
+	// hook's environment and parent processes belong to whoever committed.
+	InHook bool
+
+	// TrailerRules are matched before the built-in trailer rules.
+	TrailerRules []TrailerRule
+
+	// Staged runs detection against the staged index instead of HEAD, for
+	// a commit that is about to be created. The commit has no SHA or
+	// message yet, so auto-commit and trailer detection are skipped.
+	Staged bool
+
+	// IgnorePatterns are gitignore-style patterns for paths excluded from
+	// attribution, applied before the repo's .tempoignore.
+	IgnorePatterns []string
+
+	// Tools limits detection to the listed tools. Empty detects all.
+	Tools []Tool
+
+	// SessionMaxAge is how old a session may be and still count. Zero
+	// uses TEMPO_SESSION_MAX_AGE or the 72h default.
+	SessionMaxAge time.Duration
+
+	// OmitPaths drops file paths from the result, keeping only counts.
+	OmitPaths bool
+}
+
+// enabled reports whether tool is among the tools opts limits detection
+// to.
+func (opts Options) enabled(tool Tool) bool {
+	return len(opts.Tools) == 0 || slices.Contains(opts.Tools, tool)
+}
+
+// Detect runs the full detection pipeline for the current HEAD commit, or
+// for the staged changes when opts.Staged is set.
+func Detect(repoRoot string, opts Options) (*Attribution, error) {
+	var committedFiles []string
+	var err error
+	if opts.Staged {
+		committedFiles, err = getStagedFiles(repoRoot)
+	} else {
+		committedFiles, err = getCommittedFiles(repoRoot, "HEAD")
+	}
 	if err != nil {
 		return nil, fmt.Errorf("getting committed files: %w", err)
 	}
@@ -37,32 +84,93 @@ func Detect(repoRoot string) (*Attribution, error) {
 		return nil, nil
 	}
 
-	commitSHA, _ := gitOutput(repoRoot, "rev-parse", "HEAD")
-	commitAuthor, _ := gitOutput(repoRoot, "log", "-1", "--format=%ae")
-	commitMsg, _ := gitOutput(repoRoot, "log", "-1", "--format=%B")
-	repo := parseRepoFromRemote(repoRoot)
+	// Generated and vendored paths count neither as committed nor as AI
+	// written; only how many were left out is reported
+	committedFiles, excludedFiles := loadIgnore(repoRoot, opts.IgnorePatterns).filter(committedFiles)
+	if len(committedFiles) == 0 {
+		return nil, nil
+	}
+	excludedSet := toSet(excludedFiles)
+
+	var commitSHA, commitAuthor, commitMsg, authorName string
+	if opts.Staged {
+		commitAuthor, _ = gitOutput(repoRoot, "config", "user.email")
+	} else {
+		commitSHA, _ = gitOutput(repoRoot, "rev-parse", "HEAD")
+		commitAuthor, _ = gitOutput(repoRoot, "log", "-1", "--format=%ae")
+		commitMsg, _ = gitOutput(repoRoot, "log", "-1", "--format=%B")
+		authorName, _ = gitOutput(repoRoot, "log", "-1", "--format=%an")
+	}
+	repo := RepoFromRemote(repoRoot)
 
 	attr := &Attribution{
-		CommitSHA:    strings.TrimSpace(commitSHA),
-		CommitAuthor: strings.TrimSpace(commitAuthor),
-		Repo:         repo,
-		Timestamp:    time.Now().UTC().Format(time.RFC3339),
+		CommitSHA:     strings.TrimSpace(commitSHA),
+		CommitAuthor:  strings.TrimSpace(commitAuthor),
+		Repo:          repo,
+		Timestamp:     time.Now().UTC().Format(time.RFC3339),
+		FilesExcluded: len(excludedFiles),
 	}
 
 	committedSet := toSet(committedFiles)
-	maxAge := sessionMaxAge()
+	maxAge := opts.SessionMaxAge
+	if maxAge <= 0 {
+		maxAge = sessionMaxAge()
+	}
+	roots := opts.SessionRoots
+	if roots == nil {
+		roots = ResolveSessionRoots(nil)
+	}
+	if len(opts.Tools) > 0 {
+		// Don't read disabled tools' session data at all
+		enabledRoots := make(SessionRoots)
+		for tool, dirs := range roots {
+			if opts.enabled(tool) {
+				enabledRoots[tool] = dirs
+			}
+		}
+		roots = enabledRoots
+	}
+
+	// Every method that saw each tool, including ones deduplicated below,
+	// and the session behind each detection, for scoring
+	evidence := make(map[Tool][]Method)
+	sessions := make(map[int]*SessionInfo)
+	addEvidence := func(tool Tool, method Method) {
+		if !opts.enabled(tool) {
+			return
+		}
+		for _, m := range evidence[tool] {
+			if m == method {
+				return
+			}
+		}
+		evidence[tool] = append(evidence[tool], method)
+	}
+	addDetection := func(d Detection, session *SessionInfo) {
+		if !opts.enabled(d.Tool) {
+			return
+		}
+		switch {
+		case d.Method == MethodAutoCommit:
+			d.AIFilesExcluded = len(excludedFiles)
+		case session != nil:
+			d.AIFilesExcluded = len(intersect(session.FilesWritten, excludedSet))
+		}
+		addEvidence(d.Tool, d.Method)
+		sessions[len(attr.Detections)] = session
+		attr.Detections = append(attr.Detections, d)
+	}
 
-	// Strategy 1: File matching (HIGH confidence)
+	// Strategy 1: File matching
 	fileMatchDetected := make(map[Tool]bool)
 
 	// Claude Code
-	if session, err := detectClaudeCode(repoRoot, maxAge); err == nil && session != nil {
+	if session, err := detectClaudeCode(repoRoot, roots[ToolClaudeCode], maxAge); err == nil && session != nil {
 		matched := intersect(session.FilesWritten, committedSet)
 		if len(matched) > 0 {
 			fileMatchDetected[ToolClaudeCode] = true
-			attr.Detections = append(attr.Detections, Detection{
+			addDetection(Detection{
 				Tool:               ToolClaudeCode,
-				Confidence:         ConfidenceHigh,
 				Method:             MethodFileMatch,
 				FilesMatched:       matched,
 				FilesCommitted:     len(committedFiles),
@@ -70,34 +178,58 @@ func Detect(repoRoot string) (*Attribution, error) {
 				Model:              session.Model,
 				TokenUsage:         session.TotalTokens,
 				SessionDurationSec: session.SessionDurationSec,
-			})
+			}, session)
 		}
 	}
 
-	// Aider
-	if session, err := detectAider(repoRoot, maxAge); err == nil && session != nil {
+	// Aider: its own auto-commits are entirely AI-written; otherwise
+	// match the files its chat history reports editing
+	var aiderSession *SessionInfo
+	if opts.enabled(ToolAider) {
+		aiderSession, _ = detectAider(repoRoot, maxAge)
+	}
+	if model, ok := aiderAutoCommit(authorName, commitMsg); ok {
+		fileMatchDetected[ToolAider] = true
+		d := Detection{
+			Tool:           ToolAider,
+			Method:         MethodAutoCommit,
+			FilesMatched:   committedFiles,
+			FilesCommitted: len(committedFiles),
+			AIFiles:        len(committedFiles),
+			Model:          model,
+		}
+		if aiderSession != nil {
+			if d.Model == "" {
+				d.Model = aiderSession.Model
+			}
+			d.TokenUsage = aiderSession.TotalTokens
+			d.SessionDurationSec = aiderSession.SessionDurationSec
+		}
+		addDetection(d, aiderSession)
+	} else if session := aiderSession; session != nil {
 		matched := intersect(session.FilesWritten, committedSet)
 		if len(matched) > 0 {
 			fileMatchDetected[ToolAider] = true
-			attr.Detections = append(attr.Detections, Detection{
-				Tool:           ToolAider,
-				Confidence:     ConfidenceHigh,
-				Method:         MethodFileMatch,
-				FilesMatched:   matched,
-				FilesCommitted: len(committedFiles),
-				AIFiles:        len(matched),
-			})
+			addDetection(Detection{
+				Tool:               ToolAider,
+				Method:             MethodFileMatch,
+				FilesMatched:       matched,
+				FilesCommitted:     len(committedFiles),
+				AIFiles:            len(matched),
+				Model:              session.Model,
+				TokenUsage:         session.TotalTokens,
+				SessionDurationSec: session.SessionDurationSec,
+			}, session)
 		}
 	}
 
 	// Codex
-	if session, err := detectCodex(repoRoot, maxAge); err == nil && session != nil {
+	if session, err := detectCodex(repoRoot, roots[ToolCodex], maxAge); err == nil && session != nil {
 		matched := intersect(session.FilesWritten, committedSet)
 		if len(matched) > 0 {
 			fileMatchDetected[ToolCodex] = true
-			attr.Detections = append(attr.Detections, Detection{
+			addDetection(Detection{
 				Tool:               ToolCodex,
-				Confidence:         ConfidenceHigh,
 				Method:             MethodFileMatch,
 				FilesMatched:       matched,
 				FilesCommitted:     len(committedFiles),
@@ -105,36 +237,35 @@ func Detect(repoRoot string) (*Attribution, error) {
 				Model:              session.Model,
 				TokenUsage:         session.TotalTokens,
 				SessionDurationSec: session.SessionDurationSec,
-			})
+			}, session)
 		}
 	}
 
 	// Copilot Agent
-	if session, err := detectCopilot(repoRoot, maxAge); err == nil && session != nil {
+	if session, err := detectCopilot(repoRoot, roots[ToolCopilot], maxAge); err == nil && session != nil {
 		matched := intersect(session.FilesWritten, committedSet)
 		if len(matched) > 0 {
 			fileMatchDetected[ToolCopilot] = true
-			attr.Detections = append(attr.Detections, Detection{
+			addDetection(Detection{
 				Tool:               ToolCopilot,
-				Confidence:         ConfidenceHigh,
 				Method:             MethodFileMatch,
 				FilesMatched:       matched,
 				FilesCommitted:     len(committedFiles),
 				AIFiles:            len(matched),
 				Model:              session.Model,
+				TokenUsage:         session.TotalTokens,
 				SessionDurationSec: session.SessionDurationSec,
-			})
+			}, session)
 		}
 	}
 
 	// Cursor Agent
-	if session, err := detectCursor(repoRoot, maxAge); err == nil && session != nil {
+	if session, err := detectCursor(repoRoot, roots[ToolCursor], maxAge); err == nil && session != nil {
 		matched := intersect(session.FilesWritten, committedSet)
 		if len(matched) > 0 {
 			fileMatchDetected[ToolCursor] = true
-			attr.Detections = append(attr.Detections, Detection{
+			addDetection(Detection{
 				Tool:               ToolCursor,
-				Confidence:         ConfidenceHigh,
 				Method:             MethodFileMatch,
 				FilesMatched:       matched,
 				FilesCommitted:     len(committedFiles),
@@ -142,31 +273,76 @@ func Detect(repoRoot string) (*Attribution, error) {
 				Model:              session.Model,
 				TokenUsage:         session.TotalTokens,
 				SessionDurationSec: session.SessionDurationSec,
-			})
+			}, session)
 		}
 	}
 
-	// Strategy 2: Process detection (MEDIUM confidence)
-	for _, tool := range detectProcesses() {
+	// Cursor Tab / Cmd-K inline generations, reported separately from
+	// agent edits
+	if session, err := detectCursorInline(repoRoot, roots[ToolCursor], maxAge); err == nil && session != nil {
+		matched := intersect(session.FilesWritten, committedSet)
+		if len(matched) > 0 {
+			fileMatchDetected[ToolCursor] = true
+			addDetection(Detection{
+				Tool:               ToolCursor,
+				Method:             MethodInlineCompletion,
+				FilesMatched:       matched,
+				FilesCommitted:     len(committedFiles),
+				AIFiles:            len(matched),
+				SessionDurationSec: session.SessionDurationSec,
+			}, session)
+		}
+	}
+
+	// Strategy 2: Agent commit. The agent ran git commit itself; its session
+	// detection is upgraded rather than repeated. Other detections of the
+	// tool, like Cursor's inline completions, keep their method and count
+	// it as corroborating evidence.
+	if opts.InHook {
+		if tool, ok := detectAgentCommit(); ok {
+			addEvidence(tool, MethodAgentCommit)
+			if !markAgentCommit(attr.Detections, tool) {
+				addDetection(Detection{
+					Tool:           tool,
+					Method:         MethodAgentCommit,
+					FilesCommitted: len(committedFiles),
+				}, nil)
+			}
+			fileMatchDetected[tool] = true
+		}
+	}
+
+	// Strategy 3: Process detection
+	for _, tool := range detectProcesses(repoRoot) {
 		if !fileMatchDetected[tool] {
-			attr.Detections = append(attr.Detections, Detection{
+			addDetection(Detection{
 				Tool:           tool,
-				Confidence:     ConfidenceMedium,
 				Method:         MethodProcess,
 				FilesCommitted: len(committedFiles),
-			})
+			}, nil)
+		} else {
+			addEvidence(tool, MethodProcess)
 		}
 	}
 
-	// Strategy 3: Trailer detection (MEDIUM confidence)
+	// Strategy 4: Trailer detection
 	alreadyDetected := make(map[Tool]bool)
 	for _, d := range attr.Detections {
 		alreadyDetected[d.Tool] = true
 	}
-	for _, d := range detectTrailers(commitMsg) {
+	// Tempo's own disclosure trailer restates an earlier detection, so it
+	// may name a tool nothing else saw but never corroborates one
+	rules := compileTrailerRules(opts.TrailerRules)
+	independent := make(map[Tool]bool)
+	for _, d := range detectTrailers(commitMsg, withoutTrailerKey(rules, DisclosureTrailerKey)) {
+		independent[d.Tool] = true
+	}
+	for _, d := range detectTrailers(commitMsg, rules) {
 		if !alreadyDetected[d.Tool] {
 			d.FilesCommitted = len(committedFiles)
-			attr.Detections = append(attr.Detections, d)
+			addDetection(d, nil)
+		} else if independent[d.Tool] {
+			addEvidence(d.Tool, d.Method)
 		}
 	}
 
@@ -174,17 +350,44 @@ func Detect(repoRoot string) (*Attribution, error) {
 		return nil, nil
 	}
 
+	// Score each detection against the evidence gathered for its tool
+	commitTime := time.Now()
+	if !opts.Staged {
+		if ct, err := gitOutput(repoRoot, "log", "-1", "--format=%ct"); err == nil {
+			if sec, err := strconv.ParseInt(strings.TrimSpace(ct), 10, 64); err == nil {
+				commitTime = time.Unix(sec, 0)
+			}
+		}
+	}
+	for i := range attr.Detections {
+		d := &attr.Detections[i]
+		var corroborating []Method
+		for _, m := range evidence[d.Tool] {
+			if m != d.Method {
+				corroborating = append(corroborating, m)
+			}
+		}
+		scoreDetection(d, sessions[i], corroborating, commitTime, maxAge)
+	}
+
+	if opts.OmitPaths {
+		attr.OmitPaths()
+	}
+
 	return attr, nil
 }
 
-func detectClaudeCode(repoRoot string, maxAge time.Duration) (*SessionInfo, error) {
-	sessionDir := claudeSessionDir(repoRoot)
-	if sessionDir == "" {
-		return nil, nil
+func detectClaudeCode(repoRoot string, roots []string, maxAge time.Duration) (*SessionInfo, error) {
+	var paths []string
+	for _, projectsDir := range roots {
+		found, err := findRecentSessions(claudeSessionDir(projectsDir, repoRoot), maxAge)
+		if err != nil {
+			continue
+		}
+		paths = append(paths, found...)
 	}
-	paths, err := findRecentSessions(sessionDir, maxAge)
-	if err != nil {
-		return nil, err
+	if len(paths) == 0 {
+		return nil, nil
 	}
 
 	merged := &SessionInfo{
@@ -205,6 +408,9 @@ func detectClaudeCode(repoRoot string, maxAge time.Duration) (*SessionInfo, erro
 		}
 		merged.TotalTokens += info.TotalTokens
 		merged.SessionDurationSec += info.SessionDurationSec
+		if info.LastActivity.After(merged.LastActivity) {
+			merged.LastActivity = info.LastActivity
+		}
 	}
 
 	if len(merged.FilesWritten) == 0 {
@@ -213,23 +419,61 @@ func detectClaudeCode(repoRoot string, maxAge time.Duration) (*SessionInfo, erro
 	return merged, nil
 }
 
-func getCommittedFiles(repoRoot string) ([]string, error) {
-	output, err := gitOutput(repoRoot, "diff", "--name-only", "HEAD~1", "HEAD")
+// OmitPaths removes file paths from the attribution, keeping the counts,
+// for repos whose privacy policy forbids recording them.
+func (a *Attribution) OmitPaths() {
+	for i := range a.Detections {
+		a.Detections[i].FilesMatched = nil
+	}
+	for i := range a.Corrections {
+		a.Corrections[i].Files = nil
+	}
+}
+
+// getCommittedFiles returns the files a commit changed. A merge commit is
+// compared with its first parent, the branch it was merged into.
+func getCommittedFiles(repoRoot, rev string) ([]string, error) {
+	output, err := gitOutput(repoRoot, "diff", "--name-only", rev+"~1", rev)
 	if err != nil {
 		// First commit — diff against empty tree
 		output, err = gitOutput(repoRoot, "diff", "--name-only",
-			emptyTreeSHA, "HEAD")
+			emptyTreeSHA, rev)
 		if err != nil {
 			return nil, err
 		}
 	}
+	return splitLines(output), nil
+}
+
+// CommittedFiles returns the files a commit changed, as Detect counts
+// them: the ones excluded by ignorePatterns or the repo's .tempoignore are
+// returned separately. A merge commit is compared with its first parent.
+func CommittedFiles(repoRoot, rev string, ignorePatterns []string) (files, excluded []string, err error) {
+	files, err = getCommittedFiles(repoRoot, rev)
+	if err != nil {
+		return nil, nil, err
+	}
+	files, excluded = loadIgnore(repoRoot, ignorePatterns).filter(files)
+	return files, excluded, nil
+}
+
+// getStagedFiles returns the files staged for the next commit.
+func getStagedFiles(repoRoot string) ([]string, error) {
+	output, err := gitOutput(repoRoot, "diff", "--cached", "--name-only")
+	if err != nil {
+		return nil, err
+	}
+	return splitLines(output), nil
+}
+
+func splitLines(output string) []string {
 	var files []string
 	for _, f := range strings.Split(strings.TrimSpace(output), "\n") {
 		if f = strings.TrimSpace(f); f != "" {
 			files = append(files, f)
 		}
 	}
-	return files, nil
+	return files
 }
 
 func gitOutput(repoRoot string, args ...string) (string, error) {
@@ -239,7 +483,9 @@ func gitOutput(repoRoot string, args ...string) (string, error) {
 	return string(out), err
 }
 
-func parseRepoFromRemote(repoRoot string) string {
+// RepoFromRemote returns the "owner/repo" name of the origin remote, or ""
+// if there is none.
+func RepoFromRemote(repoRoot string) string {
 	output, err := gitOutput(repoRoot, "remote", "get-url", "origin")
 	if err != nil {
 		return ""
@@ -247,6 +493,40 @@ func parseRepoFromRemote(repoRoot string) string {
 	return parseRemoteURL(strings.TrimSpace(output))
 }
 
+// RemoteHostPath returns the origin remote as "host/owner/repo" (e.g.
+// "github.com/acme/api"), or "" if there is none or it is a local path.
+func RemoteHostPath(repoRoot string) string {
+	output, err := gitOutput(repoRoot, "remote", "get-url", "origin")
+	if err != nil {
+		return ""
+	}
+	return parseRemoteHostPath(strings.TrimSpace(output))
+}
+
+func parseRemoteHostPath(remote string) string {
+	if scheme, rest, ok := strings.Cut(remote, "://"); ok {
+		if scheme == "file" {
+			return ""
+		}
+		host, path, _ := strings.Cut(rest, "/")
+		if _, h, ok := strings.Cut(host, "@"); ok {
+			host = h
+		}
+		host, _, _ = strings.Cut(host, ":")
+		remote = host + "/" + path
+	} else {
+		// scp-like: [user@]host:owner/repo
+		if strings.HasPrefix(remote, "/") || strings.HasPrefix(remote, ".") || !strings.Contains(remote, ":") {
+			return ""
+		}
+		if _, r, ok := strings.Cut(remote, "@"); ok {
+			remote = r
+		}
+		remote = strings.Replace(remote, ":", "/", 1)
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(remote, "/"), ".git")
+}
+
 func parseRemoteURL(remote string) string {
 	// Handle SSH: git@github.com:owner/repo.git
 	if strings.HasPrefix(remote, "git@") {
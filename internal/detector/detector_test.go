@@ -1,6 +1,10 @@
 package detector
 
 import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"testing"
 )
 
@@ -77,6 +81,27 @@ func TestParseRepoFromRemote_SSH(t *testing.T) {
 	}
 }
 
+func TestParseRemoteHostPath(t *testing.T) {
+	tests := []struct {
+		remote string
+		want   string
+	}{
+		{"git@github.com:acme/api.git", "github.com/acme/api"},
+		{"github.com:acme/api", "github.com/acme/api"},
+		{"https://github.com/acme/api.git", "github.com/acme/api"},
+		{"https://user:pw@gitlab.example.com:8443/group/sub/api", "gitlab.example.com/group/sub/api"},
+		{"ssh://git@github.com:22/acme/api.git", "github.com/acme/api"},
+		{"/srv/git/api.git", ""},
+		{"file:///srv/git/api.git", ""},
+		{"../api", ""},
+	}
+	for _, tt := range tests {
+		if got := parseRemoteHostPath(tt.remote); got != tt.want {
+			t.Errorf("parseRemoteHostPath(%q): got %q, want %q", tt.remote, got, tt.want)
+		}
+	}
+}
+
 func TestToSet(t *testing.T) {
 	s := toSet([]string{"a", "b", "c", "a"})
 	if len(s) != 3 {
@@ -115,3 +140,159 @@ func TestSessionMaxAge_Invalid(t *testing.T) {
 		t.Errorf("got %v, want 72h default on invalid input", got)
 	}
 }
+
+func TestDetect_ToolsAndOmitPaths(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	t.Setenv("HOME", t.TempDir())
+
+	repoRoot := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repoRoot
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=Jane Doe (aider)", "GIT_AUTHOR_EMAIL=jane@example.com",
+			"GIT_COMMITTER_NAME=Jane Doe (aider)", "GIT_COMMITTER_EMAIL=jane@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q")
+	git("commit", "-q", "--allow-empty", "-m", "initial")
+	if err := os.WriteFile(filepath.Join(repoRoot, "a.go"), []byte("package a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	git("add", "a.go")
+	git("commit", "-q", "-m", "feat: add a")
+
+	attr, err := Detect(repoRoot, Options{SessionRoots: SessionRoots{}, OmitPaths: true})
+	if err != nil || attr == nil {
+		t.Fatalf("got %v, %v; want an attribution", attr, err)
+	}
+	for _, d := range attr.Detections {
+		if d.Tool == ToolAider && (d.FilesMatched != nil || d.AIFiles != 1) {
+			t.Errorf("paths should be omitted but counts kept, got %+v", d)
+		}
+	}
+
+	attr, err = Detect(repoRoot, Options{SessionRoots: SessionRoots{}, Tools: []Tool{ToolClaudeCode}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attr != nil {
+		for _, d := range attr.Detections {
+			if d.Tool != ToolClaudeCode {
+				t.Errorf("only claude-code is enabled, got %+v", d)
+			}
+		}
+	}
+}
+
+func TestDetect_DisclosureTrailerDoesNotCorroborate(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("CLAUDECODE", "1")
+
+	repoRoot := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repoRoot
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=Jane Doe", "GIT_AUTHOR_EMAIL=jane@example.com",
+			"GIT_COMMITTER_NAME=Jane Doe", "GIT_COMMITTER_EMAIL=jane@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q")
+	git("commit", "-q", "--allow-empty", "-m", "initial")
+	n := 0
+	score := func(msg string) float64 {
+		t.Helper()
+		n++
+		if err := os.WriteFile(filepath.Join(repoRoot, "a.go"), []byte(fmt.Sprintf("package a // %d\n", n)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		git("add", "a.go")
+		git("commit", "-q", "-m", msg)
+		attr, err := Detect(repoRoot, Options{SessionRoots: SessionRoots{}, InHook: true, Tools: []Tool{ToolClaudeCode}})
+		if err != nil || attr == nil || len(attr.Detections) != 1 {
+			t.Fatalf("got %+v, %v; want one claude-code detection", attr, err)
+		}
+		return attr.Detections[0].Score
+	}
+
+	plain := score("Add parser")
+	if got := score("Add parser\n\nAI-Assisted-By: claude-code (claude-opus-4-6)"); got != plain {
+		t.Errorf("own disclosure trailer changed the score: got %v, want %v", got, plain)
+	}
+	if got := score("Add parser\n\nCo-authored-by: Claude <noreply@anthropic.com>"); got <= plain {
+		t.Errorf("co-author trailer should corroborate: got %v, want > %v", got, plain)
+	}
+}
+
+func TestCommittedFiles(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	t.Setenv("HOME", t.TempDir())
+
+	repoRoot := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repoRoot
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=Jane Doe", "GIT_AUTHOR_EMAIL=jane@example.com",
+			"GIT_COMMITTER_NAME=Jane Doe", "GIT_COMMITTER_EMAIL=jane@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	write := func(name string) {
+		t.Helper()
+		content := "package a\n"
+		if name == IgnoreFile {
+			content = "*.gen.go\n"
+		}
+		if err := os.WriteFile(filepath.Join(repoRoot, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		git("add", name)
+	}
+	check := func(rev string, wantFiles, wantExcluded []string) {
+		t.Helper()
+		files, excluded, err := CommittedFiles(repoRoot, rev, []string{"*.lock"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !equal(files, wantFiles) || !equal(excluded, wantExcluded) {
+			t.Errorf("%s: got %v, %v; want %v, %v", rev, files, excluded, wantFiles, wantExcluded)
+		}
+	}
+
+	git("init", "-q", "-b", "main")
+	write(IgnoreFile)
+	write("a.go")
+	git("commit", "-q", "-m", "initial")
+	check("HEAD", []string{IgnoreFile, "a.go"}, nil)
+
+	git("checkout", "-q", "-b", "feature")
+	write("b.go")
+	write("b.lock")
+	write("b.gen.go")
+	git("commit", "-q", "-m", "add b")
+	check("HEAD", []string{"b.go"}, []string{"b.gen.go", "b.lock"})
+
+	// A merge is compared with the branch it was merged into
+	git("checkout", "-q", "main")
+	write("c.go")
+	git("commit", "-q", "-m", "add c")
+	git("merge", "-q", "--no-ff", "-m", "merge feature", "feature")
+	check("HEAD", []string{"b.go"}, []string{"b.gen.go", "b.lock"})
+}
@@ -0,0 +1,63 @@
+package detector
+
+import (
+	"fmt"
+)
+
+// DisclosureTrailerKey is the commit trailer that records AI involvement,
+// e.g. "AI-Assisted-By: claude-code (claude-opus-4-6); files=2/5".
+const DisclosureTrailerKey = "AI-Assisted-By"
+
+// DisclosureTrailers formats an attribution as commit trailers, one per
+// tool. Only detections backed by evidence of the tool writing code are
+// included; a process merely running is not disclosed. A tool detected
+// more than once, like Cursor's agent and inline completions, counts the
+// union of its matched files and the first model named.
+func DisclosureTrailers(attr *Attribution) []string {
+	if attr == nil {
+		return nil
+	}
+	type disclosure struct {
+		tool      Tool
+		model     string
+		files     map[string]struct{}
+		aiFiles   int
+		committed int
+	}
+	var tools []*disclosure
+	byTool := make(map[Tool]*disclosure)
+	for _, d := range attr.Detections {
+		if d.Confidence != ConfidenceHigh && d.AIFiles == 0 {
+			continue
+		}
+		t, ok := byTool[d.Tool]
+		if !ok {
+			t = &disclosure{tool: d.Tool, files: make(map[string]struct{})}
+			byTool[d.Tool] = t
+			tools = append(tools, t)
+		}
+		if t.model == "" {
+			t.model = d.Model
+		}
+		for _, f := range d.FilesMatched {
+			t.files[f] = struct{}{}
+		}
+		// Without paths (strict privacy) the union can't be taken; the
+		// largest count is the closest lower bound
+		t.aiFiles = max(t.aiFiles, d.AIFiles, len(t.files))
+		t.committed = max(t.committed, d.FilesCommitted)
+	}
+
+	var trailers []string
+	for _, t := range tools {
+		s := fmt.Sprintf("%s: %s", DisclosureTrailerKey, t.tool)
+		if t.model != "" {
+			s += fmt.Sprintf(" (%s)", t.model)
+		}
+		if t.aiFiles > 0 {
+			s += fmt.Sprintf("; files=%d/%d", t.aiFiles, t.committed)
+		}
+		trailers = append(trailers, s)
+	}
+	return trailers
+}
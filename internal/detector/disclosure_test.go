@@ -0,0 +1,48 @@
+package detector
+
+import (
+	"testing"
+)
+
+func TestDisclosureTrailers(t *testing.T) {
+	attr := &Attribution{
+		Detections: []Detection{
+			{Tool: ToolClaudeCode, Confidence: ConfidenceHigh, Method: MethodFileMatch, AIFiles: 2, FilesCommitted: 5, Model: "claude-opus-4-6"},
+			{Tool: ToolCursor, Confidence: ConfidenceMedium, Method: MethodProcess, FilesCommitted: 5},
+			{Tool: ToolCodex, Confidence: ConfidenceHigh, Method: MethodAgentCommit, FilesCommitted: 5},
+			{Tool: ToolCursor, Confidence: ConfidenceMedium, Method: MethodInlineCompletion, AIFiles: 1, FilesCommitted: 5},
+		},
+	}
+	got := DisclosureTrailers(attr)
+	want := []string{
+		"AI-Assisted-By: claude-code (claude-opus-4-6); files=2/5",
+		"AI-Assisted-By: codex",
+		"AI-Assisted-By: cursor; files=1/5",
+	}
+	if !equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// Our own trailers are recognized when the commit is later analyzed
+	dets := detectTrailers("Add parser\n\n"+want[0], builtinTrailerRules)
+	if len(dets) != 1 || dets[0].Tool != ToolClaudeCode || dets[0].Model != "claude-opus-4-6" {
+		t.Errorf("round trip: got %+v", dets)
+	}
+
+	// A tool detected twice gets one trailer counting each file once
+	attr = &Attribution{
+		Detections: []Detection{
+			{Tool: ToolCursor, Confidence: ConfidenceHigh, Method: MethodFileMatch, FilesMatched: []string{"a.go", "b.go"}, AIFiles: 2, FilesCommitted: 5},
+			{Tool: ToolCursor, Confidence: ConfidenceMedium, Method: MethodInlineCompletion, FilesMatched: []string{"b.go", "c.go"}, AIFiles: 2, FilesCommitted: 5, Model: "cursor-tab"},
+		},
+	}
+	got = DisclosureTrailers(attr)
+	want = []string{"AI-Assisted-By: cursor (cursor-tab); files=3/5"}
+	if !equal(got, want) {
+		t.Errorf("merged: got %q, want %q", got, want)
+	}
+
+	if DisclosureTrailers(nil) != nil {
+		t.Error("expected nil for nil attribution")
+	}
+}
@@ -0,0 +1,154 @@
+package detector
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// IgnoreFile lists paths excluded from attribution, in gitignore syntax.
+// Generated and vendored files (lockfiles, snapshots, codegen output)
+// would otherwise count as AI-written whenever an agent regenerates them.
+const IgnoreFile = ".tempoignore"
+
+// ignorePattern is one compiled gitignore-style pattern.
+type ignorePattern struct {
+	re     *regexp.Regexp
+	negate bool
+}
+
+// ignoreMatcher decides which repo-relative paths are excluded. As in
+// gitignore, the last matching pattern wins, so "!" patterns can
+// re-include paths excluded earlier.
+type ignoreMatcher []ignorePattern
+
+// loadIgnore compiles the extra patterns followed by the repo's
+// .tempoignore, so the repo file can override configured patterns.
+func loadIgnore(repoRoot string, extra []string) ignoreMatcher {
+	lines := append([]string(nil), extra...)
+	if f, err := os.Open(filepath.Join(repoRoot, IgnoreFile)); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		f.Close()
+	}
+
+	var m ignoreMatcher
+	for _, line := range lines {
+		if p, ok := compileIgnorePattern(line); ok {
+			m = append(m, p)
+		}
+	}
+	return m
+}
+
+// compileIgnorePattern converts a gitignore pattern to a regexp matching
+// the paths of files it excludes, including files under an excluded
+// directory. Blank lines and comments report false.
+func compileIgnorePattern(line string) (ignorePattern, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignorePattern{}, false
+	}
+
+	var p ignorePattern
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	}
+	line = strings.TrimPrefix(line, `\`)
+
+	dirOnly := strings.HasSuffix(line, "/")
+	line = strings.TrimSuffix(line, "/")
+
+	// A slash anywhere but the end anchors the pattern to the repo root;
+	// otherwise it matches at any depth
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	if line == "" {
+		return ignorePattern{}, false
+	}
+
+	var b strings.Builder
+	b.WriteString("^")
+	if !anchored {
+		b.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case strings.HasPrefix(line[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(line[i:], "/**") && i+3 == len(line):
+			b.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(line[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(line[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := line[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(line):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(line[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if dirOnly {
+		// Only directories match, i.e. the path must continue below it
+		b.WriteString("/.*$")
+	} else {
+		b.WriteString("(?:/.*)?$")
+	}
+
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return ignorePattern{}, false
+	}
+	p.re = re
+	return p, true
+}
+
+// Match reports whether path (repo-relative, slash-separated) is excluded.
+func (m ignoreMatcher) Match(path string) bool {
+	excluded := false
+	for _, p := range m {
+		if p.re.MatchString(path) {
+			excluded = !p.negate
+		}
+	}
+	return excluded
+}
+
+// filter splits files into those kept for attribution and those excluded.
+func (m ignoreMatcher) filter(files []string) (kept, excluded []string) {
+	if len(m) == 0 {
+		return files, nil
+	}
+	for _, f := range files {
+		if m.Match(filepath.ToSlash(f)) {
+			excluded = append(excluded, f)
+		} else {
+			kept = append(kept, f)
+		}
+	}
+	return kept, excluded
+}
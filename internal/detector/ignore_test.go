@@ -0,0 +1,121 @@
+package detector
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestIgnoreMatch(t *testing.T) {
+	var m ignoreMatcher
+	for _, line := range []string{
+		"# generated",
+		"",
+		"package-lock.json",
+		"vendor/",
+		"/gen",
+		"*.snap",
+		"docs/**/*.pb.go",
+		"build/**",
+		"!build/keep.txt",
+		`\#literal`,
+	} {
+		if p, ok := compileIgnorePattern(line); ok {
+			m = append(m, p)
+		}
+	}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"package-lock.json", true},
+		{"web/package-lock.json", true},
+		{"package-lock.json.bak", false},
+		{"vendor/github.com/x/y.go", true},
+		{"third_party/vendor/z.go", true},
+		{"vendor", false}, // a file named vendor is not a directory
+		{"gen/api.go", true},
+		{"src/gen/api.go", false},
+		{"ui/__snapshots__/button.snap", true},
+		{"docs/api.pb.go", true},
+		{"docs/v1/beta/api.pb.go", true},
+		{"src/api.pb.go", false},
+		{"build/out/app.js", true},
+		{"build/keep.txt", false},
+		{"#literal", true},
+		{"src/main.go", false},
+	}
+	for _, tt := range tests {
+		if got := m.Match(tt.path); got != tt.want {
+			t.Errorf("Match(%q): got %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestDetect_Ignore(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	t.Setenv("HOME", t.TempDir())
+
+	repoRoot := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repoRoot
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=Jane Doe (aider)", "GIT_AUTHOR_EMAIL=jane@example.com",
+			"GIT_COMMITTER_NAME=Jane Doe (aider)", "GIT_COMMITTER_EMAIL=jane@example.com")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q")
+	git("commit", "-q", "--allow-empty", "-m", "initial")
+	files := map[string]string{
+		"main.go":           "package main\n",
+		"go.sum":            "x\n",
+		"vendor/lib/lib.go": "package lib\n",
+		IgnoreFile:          "vendor/\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(repoRoot, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	git("add", ".")
+	git("commit", "-q", "-m", "feat: add main")
+
+	attr, err := Detect(repoRoot, Options{
+		SessionRoots:   SessionRoots{},
+		IgnorePatterns: []string{"go.sum", IgnoreFile},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attr == nil {
+		t.Fatal("expected attribution")
+	}
+	if attr.FilesExcluded != 3 {
+		t.Errorf("files excluded: got %d, want 3", attr.FilesExcluded)
+	}
+	for _, d := range attr.Detections {
+		if d.Tool != ToolAider {
+			continue
+		}
+		if d.FilesCommitted != 1 || d.AIFiles != 1 || !equal(d.FilesMatched, []string{"main.go"}) {
+			t.Errorf("files: got %+v", d)
+		}
+		if d.AIFilesExcluded != 3 {
+			t.Errorf("ai files excluded: got %d, want 3", d.AIFilesExcluded)
+		}
+		return
+	}
+	t.Fatalf("no aider detection in %+v", attr.Detections)
+}
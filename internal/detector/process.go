@@ -1,8 +1,13 @@
 package detector
 
 import (
+	"bytes"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
+	"sort"
+	"strings"
 )
 
 // processNames maps executable names to AI tools for process detection.
@@ -16,11 +21,22 @@ var processNames = map[string]Tool{
 	"codex":          ToolCodex,
 }
 
-// detectProcesses checks for running AI tool processes.
-// Only works on macOS/Linux. Returns nil on Windows.
-func detectProcesses() []Tool {
-	if runtime.GOOS == "windows" {
+// interpreters run AI tools as scripts, so the tool name is in argv[1].
+var interpreters = map[string]bool{
+	"node":    true,
+	"python":  true,
+	"python3": true,
+}
+
+// detectProcesses checks for running AI tool processes. On Linux only
+// processes working in the repo, or opened on a path inside it, count; on
+// macOS any running process does. Returns nil on Windows.
+func detectProcesses(repoRoot string) []Tool {
+	switch runtime.GOOS {
+	case "windows":
 		return nil
+	case "linux":
+		return scanProcesses("/proc", repoRoot)
 	}
 
 	var detected []Tool
@@ -38,3 +54,134 @@ func detectProcesses() []Tool {
 	}
 	return detected
 }
+
+// scanProcesses walks a /proc-style tree and returns the AI tools whose
+// processes have their cwd or a path argument inside repoRoot.
+// Processes we may not inspect (other users') are skipped.
+func scanProcesses(procRoot, repoRoot string) []Tool {
+	entries, err := os.ReadDir(procRoot)
+	if err != nil {
+		return nil
+	}
+
+	repoRoots := []string{repoRoot}
+	if resolved, err := filepath.EvalSymlinks(repoRoot); err == nil && resolved != repoRoot {
+		repoRoots = append(repoRoots, resolved)
+	}
+
+	seen := make(map[Tool]bool)
+	for _, entry := range entries {
+		if !isPID(entry.Name()) {
+			continue
+		}
+		dir := filepath.Join(procRoot, entry.Name())
+
+		comm, err := os.ReadFile(filepath.Join(dir, "comm"))
+		if err != nil {
+			continue
+		}
+		cmdline, _ := os.ReadFile(filepath.Join(dir, "cmdline"))
+		args := splitCmdline(cmdline)
+
+		tool, ok := processTool(strings.TrimSpace(string(comm)), args)
+		if !ok || seen[tool] {
+			continue
+		}
+
+		cwd, _ := os.Readlink(filepath.Join(dir, "cwd"))
+		if processInRepo(cwd, args, repoRoots) {
+			seen[tool] = true
+		}
+	}
+
+	detected := make([]Tool, 0, len(seen))
+	for tool := range seen {
+		detected = append(detected, tool)
+	}
+	sort.Slice(detected, func(i, j int) bool { return detected[i] < detected[j] })
+	return detected
+}
+
+// processTool identifies the AI tool a process belongs to from its comm
+// name, its executable, or the script an interpreter is running.
+func processTool(comm string, args []string) (Tool, bool) {
+	if tool, ok := processNames[comm]; ok {
+		return tool, true
+	}
+	if len(args) == 0 {
+		return "", false
+	}
+	exe := filepath.Base(args[0])
+	if tool, ok := processNames[exe]; ok {
+		return tool, true
+	}
+	if interpreters[exe] && len(args) > 1 {
+		if tool, ok := processNames[filepath.Base(args[1])]; ok {
+			return tool, true
+		}
+	}
+	return "", false
+}
+
+// processInRepo reports whether a process's cwd, or any path it was given
+// on the command line (including --folder-uri style flags), lies inside
+// one of repoRoots.
+func processInRepo(cwd string, args []string, repoRoots []string) bool {
+	inRepo := func(p string) bool {
+		for _, root := range repoRoots {
+			if pathContains(root, p) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if cwd != "" && inRepo(cwd) {
+		return true
+	}
+	for _, arg := range args[min(1, len(args)):] {
+		if strings.HasPrefix(arg, "-") {
+			_, value, ok := strings.Cut(arg, "=")
+			if !ok {
+				continue
+			}
+			arg = value
+		}
+		p := uriToPath(arg)
+		if p == "" {
+			continue
+		}
+		if !filepath.IsAbs(p) {
+			if cwd == "" {
+				continue
+			}
+			p = filepath.Join(cwd, p)
+		}
+		if inRepo(filepath.Clean(p)) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitCmdline splits a NUL-separated /proc/<pid>/cmdline.
+func splitCmdline(data []byte) []string {
+	data = bytes.TrimRight(data, "\x00")
+	if len(data) == 0 {
+		return nil
+	}
+	return strings.Split(string(data), "\x00")
+}
+
+// isPID reports whether a /proc entry name is a process ID.
+func isPID(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,102 @@
+package detector
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFakeProc creates a /proc/<pid> entry with the given comm, argv and cwd.
+func writeFakeProc(t *testing.T, procRoot, pid, comm string, argv []string, cwd string) {
+	t.Helper()
+	dir := filepath.Join(procRoot, pid)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "comm"), []byte(comm+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmdline := strings.Join(argv, "\x00") + "\x00"
+	if err := os.WriteFile(filepath.Join(dir, "cmdline"), []byte(cmdline), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if cwd != "" {
+		if err := os.Symlink(cwd, filepath.Join(dir, "cwd")); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestScanProcesses(t *testing.T) {
+	procRoot := t.TempDir()
+	repoRoot := filepath.Join(t.TempDir(), "myapp")
+	otherRepo := filepath.Join(t.TempDir(), "other")
+
+	// claude working in a subdirectory of the repo
+	writeFakeProc(t, procRoot, "100", "claude", []string{"claude"}, filepath.Join(repoRoot, "src"))
+	// Cursor opened on another project
+	writeFakeProc(t, procRoot, "200", "cursor", []string{"/usr/share/cursor/cursor", otherRepo}, "/")
+	// aider run through python in the repo
+	writeFakeProc(t, procRoot, "300", "python3", []string{"/usr/bin/python3", "/home/me/.local/bin/aider"}, repoRoot)
+	// codex in another repo
+	writeFakeProc(t, procRoot, "400", "codex", []string{"codex"}, otherRepo)
+	// Unrelated process in the repo
+	writeFakeProc(t, procRoot, "500", "vim", []string{"vim", "main.go"}, repoRoot)
+	// Non-PID entries are ignored
+	if err := os.MkdirAll(filepath.Join(procRoot, "self"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	got := scanProcesses(procRoot, repoRoot)
+	want := []Tool{ToolAider, ToolClaudeCode}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestScanProcesses_WorkspaceArgument(t *testing.T) {
+	procRoot := t.TempDir()
+	repoRoot := filepath.Join(t.TempDir(), "myapp")
+
+	// Cursor launched from elsewhere with the repo as its workspace
+	writeFakeProc(t, procRoot, "100", "cursor", []string{"cursor", "--folder-uri=file://" + repoRoot}, "/")
+	// A process whose cwd cannot be read (owned by another user)
+	writeFakeProc(t, procRoot, "200", "codex", []string{"codex"}, "")
+
+	got := scanProcesses(procRoot, repoRoot)
+	if len(got) != 1 || got[0] != ToolCursor {
+		t.Errorf("got %v, want [cursor]", got)
+	}
+}
+
+func TestProcessInRepo(t *testing.T) {
+	roots := []string{"/work/myapp"}
+	tests := []struct {
+		name string
+		cwd  string
+		args []string
+		want bool
+	}{
+		{"cwd is repo", "/work/myapp", []string{"claude"}, true},
+		{"cwd below repo", "/work/myapp/cmd", []string{"claude"}, true},
+		{"cwd is sibling with shared prefix", "/work/myapp2", []string{"claude"}, false},
+		{"absolute path arg", "/", []string{"cursor", "/work/myapp/main.go"}, true},
+		{"relative path arg", "/work", []string{"cursor", "myapp"}, true},
+		{"parent folder arg", "/", []string{"cursor", "/work"}, false},
+		{"flag without value", "/", []string{"cursor", "--new-window"}, false},
+		{"argv0 is not a workspace", "/", []string{"/work/myapp/bin/claude"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := processInRepo(tt.cwd, tt.args, roots); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
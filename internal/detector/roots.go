@@ -0,0 +1,188 @@
+package detector
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Session root discovery.
+//
+// Each tool stores its session data under a per-user directory whose location
+// depends on the OS, environment overrides and how the tool was packaged:
+//
+//   Claude Code: $CLAUDE_CONFIG_DIR/projects, ~/.claude/projects, $XDG_CONFIG_HOME/claude/projects
+//   Codex:       $CODEX_HOME/sessions, ~/.codex/sessions
+//   VS Code:     {Code, Code - Insiders, Code - OSS, VSCodium}/User/workspaceStorage under the
+//                OS config dir ($XDG_CONFIG_HOME on Linux), Flatpak (~/.var/app/...), Snap
+//                (~/snap/...), $VSCODE_PORTABLE and VS Code Server data dirs
+//   Cursor:      Cursor/User/workspaceStorage under the OS config dir and Cursor Server data
+//
+// Aider keeps its history inside the repo and has no session root.
+
+// SessionRoots maps each tool to the directories its session data is read from.
+type SessionRoots map[Tool][]string
+
+// ResolveSessionRoots returns the session roots for every tool that keeps
+// session data outside the repo. Roots listed in overrides (keyed by tool
+// name, e.g. "claude-code") replace the defaults for that tool; a leading
+// "~/" is expanded to the home directory.
+func ResolveSessionRoots(overrides map[string][]string) SessionRoots {
+	roots := SessionRoots{
+		ToolClaudeCode: claudeProjectsDirs(),
+		ToolCodex:      codexSessionsDirs(),
+		ToolCopilot:    vscodeBaseDirs(),
+		ToolCursor:     cursorBaseDirs(),
+	}
+	for name, dirs := range overrides {
+		var expanded []string
+		for _, d := range dirs {
+			if d = expandHome(d); d != "" {
+				expanded = append(expanded, d)
+			}
+		}
+		roots[Tool(name)] = expanded
+	}
+	return roots
+}
+
+// Tools returns the tools with configured roots in a stable order.
+func (r SessionRoots) Tools() []Tool {
+	tools := make([]Tool, 0, len(r))
+	for t := range r {
+		tools = append(tools, t)
+	}
+	sort.Slice(tools, func(i, j int) bool { return tools[i] < tools[j] })
+	return tools
+}
+
+// claudeProjectsDirs returns the Claude Code projects directories.
+// CLAUDE_CONFIG_DIR replaces the default config locations when set.
+func claudeProjectsDirs() []string {
+	if dir := os.Getenv("CLAUDE_CONFIG_DIR"); dir != "" {
+		return []string{filepath.Join(dir, "projects")}
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	return uniqueDirs(
+		filepath.Join(homeDir, ".claude", "projects"),
+		filepath.Join(xdgConfigHome(homeDir), "claude", "projects"),
+	)
+}
+
+// codexSessionsDirs returns the Codex sessions directory, honoring CODEX_HOME.
+func codexSessionsDirs() []string {
+	if dir := os.Getenv("CODEX_HOME"); dir != "" {
+		return []string{filepath.Join(dir, "sessions")}
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	return []string{filepath.Join(homeDir, ".codex", "sessions")}
+}
+
+// vscodeBaseDirs returns the VS Code workspace storage base directories
+// for the current OS. Covers VS Code, Insiders, Code - OSS and VSCodium,
+// their Flatpak and Snap packages, portable installs, and on Linux the
+// VS Code Server data used by Remote-SSH and devcontainers.
+func vscodeBaseDirs() []string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+
+	var bases []string
+	if portable := os.Getenv("VSCODE_PORTABLE"); portable != "" {
+		bases = append(bases, filepath.Join(portable, "user-data", "User", "workspaceStorage"))
+	}
+
+	products := []string{"Code", "Code - Insiders", "Code - OSS", "VSCodium"}
+	switch runtime.GOOS {
+	case "darwin":
+		appSupport := filepath.Join(homeDir, "Library", "Application Support")
+		for _, p := range products {
+			bases = append(bases, filepath.Join(appSupport, p, "User", "workspaceStorage"))
+		}
+	case "linux":
+		configDir := xdgConfigHome(homeDir)
+		for _, p := range products {
+			bases = append(bases, filepath.Join(configDir, p, "User", "workspaceStorage"))
+		}
+		sandboxed := []string{
+			filepath.Join(homeDir, ".var", "app", "com.visualstudio.code", "config", "Code"),
+			filepath.Join(homeDir, ".var", "app", "com.visualstudio.code.insiders", "config", "Code - Insiders"),
+			filepath.Join(homeDir, ".var", "app", "com.vscodium.codium", "config", "VSCodium"),
+			filepath.Join(homeDir, "snap", "code", "current", ".config", "Code"),
+			filepath.Join(homeDir, "snap", "code-insiders", "current", ".config", "Code - Insiders"),
+			filepath.Join(homeDir, "snap", "codium", "current", ".config", "VSCodium"),
+			filepath.Join(homeDir, ".vscode-server", "data"),
+			filepath.Join(homeDir, ".vscode-server-insiders", "data"),
+			filepath.Join(homeDir, ".vscodium-server", "data"),
+		}
+		for _, d := range sandboxed {
+			bases = append(bases, filepath.Join(d, "User", "workspaceStorage"))
+		}
+	}
+	return uniqueDirs(bases...)
+}
+
+// cursorBaseDirs returns the Cursor workspace storage base directories
+// for the current OS. On Linux this includes the Cursor Server data used
+// when committing from inside a Remote-SSH host or devcontainer.
+func cursorBaseDirs() []string {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{
+			filepath.Join(homeDir, "Library", "Application Support", "Cursor", "User", "workspaceStorage"),
+		}
+	case "linux":
+		return uniqueDirs(
+			filepath.Join(xdgConfigHome(homeDir), "Cursor", "User", "workspaceStorage"),
+			filepath.Join(homeDir, ".cursor-server", "data", "User", "workspaceStorage"),
+		)
+	}
+	return nil
+}
+
+// xdgConfigHome returns $XDG_CONFIG_HOME, defaulting to ~/.config.
+func xdgConfigHome(homeDir string) string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" && filepath.IsAbs(dir) {
+		return dir
+	}
+	return filepath.Join(homeDir, ".config")
+}
+
+// expandHome expands a leading "~/" to the user's home directory.
+func expandHome(p string) string {
+	if p != "~" && !strings.HasPrefix(p, "~/") {
+		return p
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, strings.TrimPrefix(p, "~"))
+}
+
+// uniqueDirs returns dirs with duplicates removed, preserving order.
+func uniqueDirs(dirs ...string) []string {
+	seen := make(map[string]bool, len(dirs))
+	var result []string
+	for _, d := range dirs {
+		if !seen[d] {
+			seen[d] = true
+			result = append(result, d)
+		}
+	}
+	return result
+}
@@ -0,0 +1,98 @@
+package detector
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestResolveSessionRoots_Defaults(t *testing.T) {
+	homeDir := t.TempDir()
+	t.Setenv("HOME", homeDir)
+	t.Setenv("CLAUDE_CONFIG_DIR", "")
+	t.Setenv("CODEX_HOME", "")
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	roots := ResolveSessionRoots(nil)
+
+	if !contains(roots[ToolClaudeCode], filepath.Join(homeDir, ".claude", "projects")) {
+		t.Errorf("claude-code roots missing ~/.claude/projects: %v", roots[ToolClaudeCode])
+	}
+	if !equal(roots[ToolCodex], []string{filepath.Join(homeDir, ".codex", "sessions")}) {
+		t.Errorf("codex roots: got %v", roots[ToolCodex])
+	}
+	if _, ok := roots[ToolAider]; ok {
+		t.Error("aider should have no session roots")
+	}
+}
+
+func TestResolveSessionRoots_EnvOverrides(t *testing.T) {
+	homeDir := t.TempDir()
+	t.Setenv("HOME", homeDir)
+	t.Setenv("CLAUDE_CONFIG_DIR", "/opt/claude")
+	t.Setenv("CODEX_HOME", "/opt/codex")
+
+	roots := ResolveSessionRoots(nil)
+
+	if !equal(roots[ToolClaudeCode], []string{"/opt/claude/projects"}) {
+		t.Errorf("claude-code roots: got %v", roots[ToolClaudeCode])
+	}
+	if !equal(roots[ToolCodex], []string{"/opt/codex/sessions"}) {
+		t.Errorf("codex roots: got %v", roots[ToolCodex])
+	}
+}
+
+func TestResolveSessionRoots_XDGAndPackaging(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("XDG and Flatpak/Snap locations are Linux-only")
+	}
+	homeDir := t.TempDir()
+	t.Setenv("HOME", homeDir)
+	t.Setenv("XDG_CONFIG_HOME", "/xdg")
+
+	roots := ResolveSessionRoots(nil)
+
+	for _, want := range []string{
+		"/xdg/Code/User/workspaceStorage",
+		"/xdg/VSCodium/User/workspaceStorage",
+		"/xdg/Code - OSS/User/workspaceStorage",
+		filepath.Join(homeDir, ".var", "app", "com.visualstudio.code", "config", "Code", "User", "workspaceStorage"),
+		filepath.Join(homeDir, "snap", "code", "current", ".config", "Code", "User", "workspaceStorage"),
+	} {
+		if !contains(roots[ToolCopilot], want) {
+			t.Errorf("copilot roots missing %q", want)
+		}
+	}
+	if !contains(roots[ToolCursor], "/xdg/Cursor/User/workspaceStorage") {
+		t.Errorf("cursor roots missing XDG location: %v", roots[ToolCursor])
+	}
+	if !contains(roots[ToolClaudeCode], "/xdg/claude/projects") {
+		t.Errorf("claude-code roots missing XDG location: %v", roots[ToolClaudeCode])
+	}
+}
+
+func TestResolveSessionRoots_ConfigOverrides(t *testing.T) {
+	homeDir := t.TempDir()
+	t.Setenv("HOME", homeDir)
+
+	roots := ResolveSessionRoots(map[string][]string{
+		"cursor": {"~/cursor-data/workspaceStorage", "/mnt/cursor"},
+	})
+
+	want := []string{filepath.Join(homeDir, "cursor-data", "workspaceStorage"), "/mnt/cursor"}
+	if !equal(roots[ToolCursor], want) {
+		t.Errorf("cursor roots: got %v, want %v", roots[ToolCursor], want)
+	}
+	if len(roots[ToolCodex]) == 0 {
+		t.Error("codex roots should keep their defaults")
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package detector
+
+import (
+	"math"
+	"time"
+)
+
+// Detections are scored from 0 to 1 by weighing the evidence behind them:
+//
+//   - the detection method itself (methodWeights)
+//   - the share of committed files the tool wrote
+//   - how recently the tool was active before the commit
+//   - how many other methods saw the same tool
+//   - the share of reviewed edits the user rejected
+//
+// The score then sets Confidence: high from 0.7, medium from 0.4, low below.
+
+// methodWeights is the base score of each detection method.
+var methodWeights = map[Method]float64{
+	MethodAutoCommit:       0.95,
+	MethodAgentCommit:      0.9,
+	MethodFileMatch:        0.7,
+	MethodCoAuthorTrailer:  0.5,
+	MethodInlineCompletion: 0.45,
+	MethodProcess:          0.35,
+}
+
+const (
+	scoreHigh   = 0.7
+	scoreMedium = 0.4
+
+	// Activity within recentActivity of the commit counts as fully recent;
+	// older activity decays linearly to zero at the session max age.
+	recentActivity = time.Hour
+
+	// maxCorroborating caps how many corroborating methods add to a score.
+	maxCorroborating = 2
+)
+
+// confidenceForScore maps a score to its confidence tier.
+func confidenceForScore(score float64) Confidence {
+	switch {
+	case score >= scoreHigh:
+		return ConfidenceHigh
+	case score >= scoreMedium:
+		return ConfidenceMedium
+	default:
+		return ConfidenceLow
+	}
+}
+
+// scoreDetection sets d's Score, ScoreFactors and Confidence. session is
+// the session the detection came from, if any; corroborating lists other
+// methods that detected the same tool for this commit.
+func scoreDetection(d *Detection, session *SessionInfo, corroborating []Method, commitTime time.Time, maxAge time.Duration) {
+	factors := &ScoreFactors{
+		Base:          methodWeights[d.Method],
+		Corroborating: corroborating,
+	}
+	score := factors.Base
+
+	if d.FilesCommitted > 0 && d.AIFiles > 0 {
+		factors.MatchedRatio = float64(d.AIFiles) / float64(d.FilesCommitted)
+		score += 0.2 * (factors.MatchedRatio - 0.5)
+	}
+
+	if session != nil {
+		if !session.LastActivity.IsZero() {
+			age := max(commitTime.Sub(session.LastActivity), 0)
+			ageSec := int64(age.Seconds())
+			factors.LastEditAgeSec = &ageSec
+			score += 0.1 * (activityRecency(age, maxAge) - 0.5)
+		}
+
+		factors.EditsAccepted = session.EditsAccepted
+		factors.EditsRejected = session.EditsRejected
+		if reviewed := session.EditsAccepted + session.EditsRejected; reviewed > 0 {
+			score -= 0.3 * float64(session.EditsRejected) / float64(reviewed)
+		}
+	}
+
+	score += 0.1 * float64(min(len(corroborating), maxCorroborating))
+
+	d.Score = math.Round(min(max(score, 0), 1)*100) / 100
+	d.ScoreFactors = factors
+	d.Confidence = confidenceForScore(d.Score)
+}
+
+// activityRecency rates activity age from 1 (within recentActivity) down
+// to 0 (at or beyond maxAge).
+func activityRecency(age, maxAge time.Duration) float64 {
+	if age <= recentActivity {
+		return 1
+	}
+	if maxAge <= recentActivity || age >= maxAge {
+		return 0
+	}
+	return 1 - float64(age-recentActivity)/float64(maxAge-recentActivity)
+}
@@ -0,0 +1,125 @@
+package detector
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScoreDetection(t *testing.T) {
+	commit := time.Date(2026, 2, 12, 17, 0, 0, 0, time.UTC)
+	maxAge := 72 * time.Hour
+
+	tests := []struct {
+		name          string
+		d             Detection
+		session       *SessionInfo
+		corroborating []Method
+		wantScore     float64
+		wantConf      Confidence
+	}{
+		{
+			name:      "full fresh file match",
+			d:         Detection{Method: MethodFileMatch, FilesCommitted: 2, AIFiles: 2},
+			session:   &SessionInfo{LastActivity: commit.Add(-5 * time.Minute)},
+			wantScore: 0.85,
+			wantConf:  ConfidenceHigh,
+		},
+		{
+			name:      "one of ten files, stale session",
+			d:         Detection{Method: MethodFileMatch, FilesCommitted: 10, AIFiles: 1},
+			session:   &SessionInfo{LastActivity: commit.Add(-72 * time.Hour)},
+			wantScore: 0.57,
+			wantConf:  ConfidenceMedium,
+		},
+		{
+			name:      "process alone",
+			d:         Detection{Method: MethodProcess, FilesCommitted: 3},
+			wantScore: 0.35,
+			wantConf:  ConfidenceLow,
+		},
+		{
+			name:          "process corroborated by trailer",
+			d:             Detection{Method: MethodProcess, FilesCommitted: 3},
+			corroborating: []Method{MethodCoAuthorTrailer},
+			wantScore:     0.45,
+			wantConf:      ConfidenceMedium,
+		},
+		{
+			name:          "corroboration is capped",
+			d:             Detection{Method: MethodProcess, FilesCommitted: 3},
+			corroborating: []Method{MethodCoAuthorTrailer, MethodFileMatch, MethodAgentCommit},
+			wantScore:     0.55,
+			wantConf:      ConfidenceMedium,
+		},
+		{
+			name:      "mostly rejected edits",
+			d:         Detection{Method: MethodFileMatch, FilesCommitted: 4, AIFiles: 2},
+			session:   &SessionInfo{LastActivity: commit, EditsAccepted: 1, EditsRejected: 3},
+			wantScore: 0.53,
+			wantConf:  ConfidenceMedium,
+		},
+		{
+			name:      "auto-commit is clamped",
+			d:         Detection{Method: MethodAutoCommit, FilesCommitted: 3, AIFiles: 3},
+			session:   &SessionInfo{LastActivity: commit},
+			wantScore: 1,
+			wantConf:  ConfidenceHigh,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := tt.d
+			scoreDetection(&d, tt.session, tt.corroborating, commit, maxAge)
+			if d.Score != tt.wantScore {
+				t.Errorf("score: got %v, want %v", d.Score, tt.wantScore)
+			}
+			if d.Confidence != tt.wantConf {
+				t.Errorf("confidence: got %q, want %q", d.Confidence, tt.wantConf)
+			}
+			if d.ScoreFactors == nil || d.ScoreFactors.Base != methodWeights[d.Method] {
+				t.Errorf("factors: got %+v", d.ScoreFactors)
+			}
+		})
+	}
+}
+
+func TestScoreFactors(t *testing.T) {
+	commit := time.Now()
+	d := Detection{Method: MethodFileMatch, FilesCommitted: 4, AIFiles: 1}
+	session := &SessionInfo{LastActivity: commit.Add(-2 * time.Minute), EditsAccepted: 2, EditsRejected: 1}
+	scoreDetection(&d, session, []Method{MethodProcess}, commit, 72*time.Hour)
+
+	f := d.ScoreFactors
+	if f.MatchedRatio != 0.25 {
+		t.Errorf("matched ratio: got %v, want 0.25", f.MatchedRatio)
+	}
+	if f.LastEditAgeSec == nil || *f.LastEditAgeSec != 120 {
+		t.Errorf("last edit age: got %v, want 120", f.LastEditAgeSec)
+	}
+	if f.EditsAccepted != 2 || f.EditsRejected != 1 {
+		t.Errorf("edits: got %d/%d, want 2/1", f.EditsAccepted, f.EditsRejected)
+	}
+	if len(f.Corroborating) != 1 || f.Corroborating[0] != MethodProcess {
+		t.Errorf("corroborating: got %v", f.Corroborating)
+	}
+}
+
+func TestActivityRecency(t *testing.T) {
+	maxAge := 25 * time.Hour
+	tests := []struct {
+		age  time.Duration
+		want float64
+	}{
+		{0, 1},
+		{time.Hour, 1},
+		{13 * time.Hour, 0.5},
+		{25 * time.Hour, 0},
+		{100 * time.Hour, 0},
+	}
+	for _, tt := range tests {
+		if got := activityRecency(tt.age, maxAge); got != tt.want {
+			t.Errorf("activityRecency(%v): got %v, want %v", tt.age, got, tt.want)
+		}
+	}
+}
@@ -1,48 +1,224 @@
 package detector
 
 import (
+	"fmt"
+	"os/exec"
+	"regexp"
 	"strings"
 )
 
-// Known AI tool email patterns in Co-Authored-By trailers.
-var trailerPatterns = map[string]Tool{
-	"noreply@anthropic.com": ToolClaudeCode,
-	"copilot@github.com":    ToolCopilot,
-	"cursor@cursor.com":     ToolCursor,
+// TrailerRule maps a commit message trailer to an AI tool.
+//
+// Key is the trailer key, matched case-insensitively (e.g. "Co-authored-by").
+// An empty Key matches Pattern against every line of the message instead,
+// for markers that are not trailers ("Generated by Aider").
+//
+// Pattern is a regular expression matched against the trailer value. It may
+// capture a named group "model" for the model name, and a named group
+// "tool" when Tool is empty.
+type TrailerRule struct {
+	Key     string `json:"key"`
+	Pattern string `json:"pattern"`
+	Tool    string `json:"tool,omitempty"`
 }
 
-// detectTrailers parses a commit message and returns detections from
-// Co-Authored-By trailers and other AI attribution patterns.
-func detectTrailers(commitMsg string) []Detection {
+// Validate reports whether the rule's pattern compiles and names a tool.
+func (r TrailerRule) Validate() error {
+	_, err := r.compile()
+	return err
+}
+
+// trailerRule is a compiled TrailerRule.
+type trailerRule struct {
+	key  string
+	re   *regexp.Regexp
+	tool Tool
+}
+
+func (r TrailerRule) compile() (trailerRule, error) {
+	re, err := regexp.Compile(r.Pattern)
+	if err != nil {
+		return trailerRule{}, fmt.Errorf("trailer rule %q: %w", r.Pattern, err)
+	}
+	if r.Tool == "" && re.SubexpIndex("tool") < 0 {
+		return trailerRule{}, fmt.Errorf("trailer rule %q: no tool and no (?P<tool>...) group", r.Pattern)
+	}
+	return trailerRule{key: strings.ToLower(r.Key), re: re, tool: Tool(r.Tool)}, nil
+}
+
+// compileTrailerRules compiles custom rules ahead of the built-in ones so
+// they take precedence. Invalid custom rules are skipped.
+func compileTrailerRules(custom []TrailerRule) []trailerRule {
+	rules := make([]trailerRule, 0, len(custom)+len(builtinTrailerRules))
+	for _, r := range custom {
+		if c, err := r.compile(); err == nil {
+			rules = append(rules, c)
+		}
+	}
+	return append(rules, builtinTrailerRules...)
+}
+
+// trailerTools names each tool the way people write it in trailer values.
+var trailerTools = []struct {
+	name string
+	tool Tool
+}{
+	{`claude(?:[- ]code)?`, ToolClaudeCode},
+	{`(?:github[- ])?copilot`, ToolCopilot},
+	{`cursor`, ToolCursor},
+	{`(?:openai[- ])?codex`, ToolCodex},
+	{`aider`, ToolAider},
+	{`gemini(?:[- ](?:cli|code[- ]assist))?`, ToolGemini},
+	{`windsurf|codeium`, ToolWindsurf},
+}
+
+// disclosureKeys are trailers whose value names the assisting tool, e.g.
+// "Assisted-by: Claude Code (claude-opus-4-6)".
+var disclosureKeys = []string{"assisted-by", "generated-by", "ai-assistant", "ai-assisted-by"}
+
+// builtinTrailerRules are the trailer formats recognized out of the box.
+var builtinTrailerRules = func() []trailerRule {
+	rules := []trailerRule{
+		// Bot identities in Co-authored-by
+		{"co-authored-by", regexp.MustCompile(`(?i)noreply@anthropic\.com`), ToolClaudeCode},
+		{"co-authored-by", regexp.MustCompile(`(?i)copilot@github\.com|\+copilot@users\.noreply\.github\.com`), ToolCopilot},
+		{"co-authored-by", regexp.MustCompile(`(?i)(?:cursor|cursoragent)@cursor\.com`), ToolCursor},
+		{"co-authored-by", regexp.MustCompile(`(?i)codex@openai\.com|chatgpt-codex-connector`), ToolCodex},
+		{"co-authored-by", regexp.MustCompile(`(?i)gemini-code-assist|gemini-cli`), ToolGemini},
+		{"co-authored-by", regexp.MustCompile(`(?i)@(?:windsurf|codeium)\.com`), ToolWindsurf},
+		{"co-authored-by", regexp.MustCompile(`(?i)^aider\b(?:\s*\((?P<model>[^)]+)\))?`), ToolAider},
+
+		// Aider's commit message markers
+		{"", regexp.MustCompile(`(?i)generated by aider`), ToolAider},
+		{"", regexp.MustCompile(`(?i)^\s*aider:`), ToolAider},
+	}
+	for _, key := range disclosureKeys {
+		for _, t := range trailerTools {
+			re := regexp.MustCompile(`(?i)^(?:` + t.name + `)\b(?:\s*\((?P<model>[^)]+)\))?`)
+			rules = append(rules, trailerRule{key, re, t.tool})
+		}
+	}
+	return rules
+}()
+
+// withoutTrailerKey returns the rules that do not match trailers with key.
+func withoutTrailerKey(rules []trailerRule, key string) []trailerRule {
+	key = strings.ToLower(key)
+	var kept []trailerRule
+	for _, r := range rules {
+		if r.key != key {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// trailer is one parsed "Key: value" trailer.
+type trailer struct {
+	key   string
+	value string
+}
+
+// parseTrailers returns the trailers of a commit message using
+// git interpret-trailers, falling back to a simple parse of the last
+// paragraph if git is unavailable.
+func parseTrailers(commitMsg string) []trailer {
+	cmd := exec.Command("git", "interpret-trailers", "--parse")
+	cmd.Stdin = strings.NewReader(commitMsg)
+	out, err := cmd.Output()
+	if err != nil {
+		return parseTrailersFallback(commitMsg)
+	}
+	var trailers []trailer
+	for _, line := range strings.Split(string(out), "\n") {
+		if t, ok := splitTrailer(line); ok {
+			trailers = append(trailers, t)
+		}
+	}
+	return trailers
+}
+
+// trailerLineRe matches a "Key: value" trailer line.
+var trailerLineRe = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9-]*)\s*:\s*(.*)$`)
+
+func splitTrailer(line string) (trailer, bool) {
+	m := trailerLineRe.FindStringSubmatch(strings.TrimSpace(line))
+	if m == nil {
+		return trailer{}, false
+	}
+	return trailer{key: m[1], value: strings.TrimSpace(m[2])}, true
+}
+
+// parseTrailersFallback reads "Key: value" lines from the last paragraph
+// of the message, unfolding indented continuation lines.
+func parseTrailersFallback(commitMsg string) []trailer {
+	paragraphs := strings.Split(strings.TrimSpace(strings.ReplaceAll(commitMsg, "\r\n", "\n")), "\n\n")
+	if len(paragraphs) < 2 {
+		return nil
+	}
+	var trailers []trailer
+	for _, line := range strings.Split(paragraphs[len(paragraphs)-1], "\n") {
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(trailers) > 0 {
+			trailers[len(trailers)-1].value += " " + strings.TrimSpace(line)
+			continue
+		}
+		if t, ok := splitTrailer(line); ok {
+			trailers = append(trailers, t)
+		}
+	}
+	return trailers
+}
+
+// detectTrailers parses a commit message and returns detections from its
+// attribution trailers and other AI markers, one per tool.
+func detectTrailers(commitMsg string, rules []trailerRule) []Detection {
 	var detections []Detection
-	seen := make(map[Tool]bool)
-
-	for _, line := range strings.Split(commitMsg, "\n") {
-		lower := strings.ToLower(strings.TrimSpace(line))
-
-		// Check Co-Authored-By pattern
-		if strings.HasPrefix(lower, "co-authored-by:") {
-			for pattern, tool := range trailerPatterns {
-				if strings.Contains(lower, pattern) && !seen[tool] {
-					seen[tool] = true
-					detections = append(detections, Detection{
-						Tool:       tool,
-						Confidence: ConfidenceMedium,
-						Method:     MethodCoAuthorTrailer,
-					})
-				}
+	index := make(map[Tool]int)
+
+	add := func(r trailerRule, value string) {
+		m := r.re.FindStringSubmatch(value)
+		if m == nil {
+			return
+		}
+		tool := r.tool
+		if i := r.re.SubexpIndex("tool"); tool == "" && i >= 0 {
+			tool = Tool(strings.ToLower(strings.TrimSpace(m[i])))
+		}
+		if tool == "" {
+			return
+		}
+		var model string
+		if i := r.re.SubexpIndex("model"); i >= 0 {
+			model = strings.TrimSpace(m[i])
+		}
+		if i, ok := index[tool]; ok {
+			if detections[i].Model == "" {
+				detections[i].Model = model
 			}
+			return
 		}
+		index[tool] = len(detections)
+		detections = append(detections, Detection{
+			Tool:       tool,
+			Confidence: ConfidenceMedium,
+			Method:     MethodCoAuthorTrailer,
+			Model:      model,
+		})
+	}
 
-		// Check aider patterns
-		if (strings.Contains(lower, "generated by aider") ||
-			strings.HasPrefix(lower, "aider:")) && !seen[ToolAider] {
-			seen[ToolAider] = true
-			detections = append(detections, Detection{
-				Tool:       ToolAider,
-				Confidence: ConfidenceMedium,
-				Method:     MethodCoAuthorTrailer,
-			})
+	trailers := parseTrailers(commitMsg)
+	lines := strings.Split(commitMsg, "\n")
+	for _, r := range rules {
+		if r.key == "" {
+			for _, line := range lines {
+				add(r, line)
+			}
+			continue
+		}
+		for _, t := range trailers {
+			if strings.ToLower(t.key) == r.key {
+				add(r, t.value)
+			}
 		}
 	}
 	return detections
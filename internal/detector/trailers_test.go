@@ -6,7 +6,7 @@ import (
 
 func TestDetectTrailers_ClaudeCode(t *testing.T) {
 	msg := "Fix auth bug\n\nCo-Authored-By: Claude <noreply@anthropic.com>"
-	dets := detectTrailers(msg)
+	dets := detectTrailers(msg, builtinTrailerRules)
 	if len(dets) != 1 {
 		t.Fatalf("expected 1 detection, got %d", len(dets))
 	}
@@ -23,7 +23,7 @@ func TestDetectTrailers_ClaudeCode(t *testing.T) {
 
 func TestDetectTrailers_Copilot(t *testing.T) {
 	msg := "Add feature\n\nCo-authored-by: GitHub Copilot <copilot@github.com>"
-	dets := detectTrailers(msg)
+	dets := detectTrailers(msg, builtinTrailerRules)
 	if len(dets) != 1 {
 		t.Fatalf("expected 1 detection, got %d", len(dets))
 	}
@@ -34,7 +34,7 @@ func TestDetectTrailers_Copilot(t *testing.T) {
 
 func TestDetectTrailers_MixedCase(t *testing.T) {
 	msg := "Fix bug\n\nCO-AUTHORED-BY: Claude <noreply@anthropic.com>"
-	dets := detectTrailers(msg)
+	dets := detectTrailers(msg, builtinTrailerRules)
 	if len(dets) != 1 {
 		t.Fatalf("expected 1 detection, got %d", len(dets))
 	}
@@ -45,7 +45,7 @@ func TestDetectTrailers_MixedCase(t *testing.T) {
 
 func TestDetectTrailers_Multiple(t *testing.T) {
 	msg := "Fix bug\n\nCo-Authored-By: Claude <noreply@anthropic.com>\nCo-authored-by: GitHub Copilot <copilot@github.com>"
-	dets := detectTrailers(msg)
+	dets := detectTrailers(msg, builtinTrailerRules)
 	if len(dets) != 2 {
 		t.Fatalf("expected 2 detections, got %d", len(dets))
 	}
@@ -60,7 +60,7 @@ func TestDetectTrailers_Multiple(t *testing.T) {
 
 func TestDetectTrailers_Aider(t *testing.T) {
 	msg := "aider: fix authentication flow"
-	dets := detectTrailers(msg)
+	dets := detectTrailers(msg, builtinTrailerRules)
 	if len(dets) != 1 {
 		t.Fatalf("expected 1 detection, got %d", len(dets))
 	}
@@ -71,7 +71,7 @@ func TestDetectTrailers_Aider(t *testing.T) {
 
 func TestDetectTrailers_AiderGenerated(t *testing.T) {
 	msg := "Fix login bug\n\nGenerated by Aider"
-	dets := detectTrailers(msg)
+	dets := detectTrailers(msg, builtinTrailerRules)
 	if len(dets) != 1 {
 		t.Fatalf("expected 1 detection, got %d", len(dets))
 	}
@@ -82,7 +82,7 @@ func TestDetectTrailers_AiderGenerated(t *testing.T) {
 
 func TestDetectTrailers_NoTrailers(t *testing.T) {
 	msg := "Just a regular commit message\n\nWith some description"
-	dets := detectTrailers(msg)
+	dets := detectTrailers(msg, builtinTrailerRules)
 	if len(dets) != 0 {
 		t.Errorf("expected 0 detections, got %d: %+v", len(dets), dets)
 	}
@@ -90,8 +90,91 @@ func TestDetectTrailers_NoTrailers(t *testing.T) {
 
 func TestDetectTrailers_DuplicateIgnored(t *testing.T) {
 	msg := "Fix\n\nCo-Authored-By: Claude <noreply@anthropic.com>\nCo-authored-by: Claude Opus <noreply@anthropic.com>"
-	dets := detectTrailers(msg)
+	dets := detectTrailers(msg, builtinTrailerRules)
 	if len(dets) != 1 {
 		t.Fatalf("expected 1 detection (deduped), got %d", len(dets))
 	}
 }
+
+func TestDetectTrailers_DisclosureTrailers(t *testing.T) {
+	tests := []struct {
+		name      string
+		msg       string
+		wantTool  Tool
+		wantModel string
+	}{
+		{"assisted-by with model", "Add parser\n\nAssisted-by: Claude Code (claude-opus-4-6)", ToolClaudeCode, "claude-opus-4-6"},
+		{"generated-by", "Add parser\n\nGenerated-by: GitHub Copilot", ToolCopilot, ""},
+		{"ai-assistant", "Add parser\n\nAI-Assistant: cursor", ToolCursor, ""},
+		{"ai-assisted-by tempo format", "Add parser\n\nAI-Assisted-By: codex (gpt-5-codex); files=2/5", ToolCodex, "gpt-5-codex"},
+		{"gemini bot", "Add parser\n\nCo-authored-by: gemini-code-assist[bot] <176961590+gemini-code-assist[bot]@users.noreply.github.com>", ToolGemini, ""},
+		{"windsurf", "Add parser\n\nCo-authored-by: Windsurf <noreply@windsurf.com>", ToolWindsurf, ""},
+		{"aider co-author model", "Add parser\n\nCo-authored-by: aider (gpt-4o) <noreply@aider.chat>", ToolAider, "gpt-4o"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dets := detectTrailers(tt.msg, builtinTrailerRules)
+			if len(dets) != 1 {
+				t.Fatalf("expected 1 detection, got %+v", dets)
+			}
+			if dets[0].Tool != tt.wantTool || dets[0].Model != tt.wantModel {
+				t.Errorf("got %q/%q, want %q/%q", dets[0].Tool, dets[0].Model, tt.wantTool, tt.wantModel)
+			}
+		})
+	}
+}
+
+func TestDetectTrailers_NotInTrailerBlock(t *testing.T) {
+	// Only the final paragraph holds trailers
+	msg := "Explain setup\n\nAssisted-by: claude is mentioned in the body here.\nMore prose follows.\n\nSigned-off-by: Jane <jane@example.com>"
+	dets := detectTrailers(msg, builtinTrailerRules)
+	if len(dets) != 0 {
+		t.Errorf("expected 0 detections, got %+v", dets)
+	}
+}
+
+func TestDetectTrailers_CustomRules(t *testing.T) {
+	rules := compileTrailerRules([]TrailerRule{
+		{Key: "X-Internal-AI", Pattern: `^(?P<tool>[a-z-]+)/(?P<model>\S+)$`},
+		{Key: "Assisted-by", Pattern: `(?i)^claude code \((?P<model>[^)]+)\)`, Tool: "claude-code"},
+		{Key: "Broken", Pattern: `(`, Tool: "x"},
+	})
+
+	msg := "Add parser\n\nX-Internal-AI: devbot/devbot-large-2\nAssisted-by: Claude Code (opus-internal)"
+	dets := detectTrailers(msg, rules)
+	if len(dets) != 2 {
+		t.Fatalf("expected 2 detections, got %+v", dets)
+	}
+	if dets[0].Tool != "devbot" || dets[0].Model != "devbot-large-2" {
+		t.Errorf("custom tool: got %+v", dets[0])
+	}
+	if dets[1].Tool != ToolClaudeCode || dets[1].Model != "opus-internal" {
+		t.Errorf("custom rule precedence: got %+v", dets[1])
+	}
+}
+
+func TestTrailerRule_Validate(t *testing.T) {
+	if err := (TrailerRule{Key: "A", Pattern: `(`, Tool: "x"}).Validate(); err == nil {
+		t.Error("expected error for bad regex")
+	}
+	if err := (TrailerRule{Key: "A", Pattern: `bot`}).Validate(); err == nil {
+		t.Error("expected error for rule without tool")
+	}
+	if err := (TrailerRule{Key: "A", Pattern: `(?P<tool>\w+)`}).Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestParseTrailersFallback(t *testing.T) {
+	msg := "Subject\n\nBody text.\n\nCo-authored-by: Claude\n  <noreply@anthropic.com>\nAssisted-by: cursor"
+	got := parseTrailersFallback(msg)
+	if len(got) != 2 {
+		t.Fatalf("got %+v", got)
+	}
+	if got[0].key != "Co-authored-by" || got[0].value != "Claude <noreply@anthropic.com>" {
+		t.Errorf("unfolded trailer: got %+v", got[0])
+	}
+	if parseTrailersFallback("Subject only") != nil {
+		t.Error("expected no trailers without a trailer paragraph")
+	}
+}
@@ -1,11 +1,14 @@
 package detector
 
+import "time"
+
 // Confidence levels for AI tool detection.
 type Confidence string
 
 const (
 	ConfidenceHigh   Confidence = "high"
 	ConfidenceMedium Confidence = "medium"
+	ConfidenceLow    Confidence = "low"
 )
 
 // Method describes how the AI tool was detected.
@@ -15,6 +18,18 @@ const (
 	MethodFileMatch       Method = "file-match"
 	MethodProcess         Method = "process"
 	MethodCoAuthorTrailer Method = "co-author-trailer"
+	// MethodInlineCompletion covers editor inline generations (e.g. Cursor
+	// Tab completions and Cmd-K edits) matched against committed files.
+	MethodInlineCompletion Method = "inline-completion"
+	// MethodAutoCommit marks a commit the AI tool made itself (e.g. Aider's
+	// auto-commits), so every committed file is attributed to it.
+	MethodAutoCommit Method = "auto-commit"
+	// MethodAgentCommit marks a commit made from inside an AI agent's shell,
+	// i.e. the agent ran git commit itself.
+	MethodAgentCommit Method = "agent-commit"
+	// MethodManual marks a detection a developer recorded by hand with
+	// tempo-cli annotate.
+	MethodManual Method = "manual"
 )
 
 // Tool identifies an AI coding tool.
@@ -26,8 +41,13 @@ const (
 	ToolCursor     Tool = "cursor"
 	ToolCopilot    Tool = "copilot"
 	ToolCodex      Tool = "codex"
+	ToolGemini     Tool = "gemini"
+	ToolWindsurf   Tool = "windsurf"
 )
 
+// knownTools lists every Tool, in the order above.
+var knownTools = []Tool{ToolClaudeCode, ToolAider, ToolCursor, ToolCopilot, ToolCodex, ToolGemini, ToolWindsurf}
+
 // Detection represents a single AI tool detection for a commit.
 type Detection struct {
 	Tool               Tool       `json:"tool"`
@@ -39,15 +59,50 @@ type Detection struct {
 	Model              string     `json:"model,omitempty"`
 	TokenUsage         int64      `json:"token_usage,omitempty"`
 	SessionDurationSec int64      `json:"session_duration_sec,omitempty"`
+
+	// AIFilesExcluded counts files the tool wrote in this commit that are
+	// excluded by .tempoignore and so not in AIFiles.
+	AIFilesExcluded int `json:"ai_files_excluded,omitempty"`
+
+	// Score is the 0-1 evidence score Confidence is derived from.
+	Score        float64       `json:"score"`
+	ScoreFactors *ScoreFactors `json:"score_factors,omitempty"`
+}
+
+// ScoreFactors records the evidence that went into a detection's score.
+type ScoreFactors struct {
+	// Base is the weight of the detection method on its own.
+	Base float64 `json:"base"`
+	// MatchedRatio is AI files over committed files, when files matched.
+	MatchedRatio float64 `json:"matched_ratio,omitempty"`
+	// LastEditAgeSec is how long before the commit the session was last
+	// active, when known.
+	LastEditAgeSec *int64 `json:"last_edit_age_sec,omitempty"`
+	// Corroborating lists other methods that detected the same tool.
+	Corroborating []Method `json:"corroborating,omitempty"`
+	EditsAccepted int      `json:"edits_accepted,omitempty"`
+	EditsRejected int      `json:"edits_rejected,omitempty"`
 }
 
 // Attribution is the full payload for one commit.
 type Attribution struct {
+	// ID identifies the record to the API across resends. It is set when
+	// the record is queued for upload.
+	ID string `json:"id,omitempty"`
+
 	CommitSHA    string      `json:"commit_sha"`
 	CommitAuthor string      `json:"commit_author"`
 	Repo         string      `json:"repo"`
 	Timestamp    string      `json:"timestamp"`
 	Detections   []Detection `json:"detections"`
+
+	// FilesExcluded counts committed files left out of attribution by
+	// .tempoignore or configured ignore patterns.
+	FilesExcluded int `json:"files_excluded,omitempty"`
+
+	// Corrections are the manual corrections applied to Detections, oldest
+	// first.
+	Corrections []Correction `json:"corrections,omitempty"`
 }
 
 // SessionInfo holds metadata extracted from an AI tool session.
@@ -57,4 +112,13 @@ type SessionInfo struct {
 	Model              string
 	TotalTokens        int64
 	SessionDurationSec int64
+
+	// LastActivity is the time of the session's most recent recorded
+	// activity, zero if unknown.
+	LastActivity time.Time
+
+	// EditsAccepted and EditsRejected count edits the user reviewed, for
+	// tools that record a decision (Cursor, Copilot).
+	EditsAccepted int
+	EditsRejected int
 }
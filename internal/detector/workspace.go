@@ -0,0 +1,247 @@
+package detector
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// VS Code-style workspace storage discovery, shared by Copilot and Cursor.
+//
+// Each window gets a directory under {root}/{hash}/ whose workspace.json
+// names what was opened:
+//   {"folder": "file:///path/to/folder"}                    (single folder)
+//   {"configuration": "file:///path/to/x.code-workspace"}   (multi-root)
+//
+// Older builds write the multi-root key as "workspace". A .code-workspace
+// file is JSON with comments listing its folders:
+//   {"folders": [{"path": "../api"}, {"uri": "file:///abs/web"}]}
+//
+// A workspace covers the repo when one of its folders is the repo root or
+// a parent of it, e.g. a monorepo parent opened as a single folder.
+
+// workspaceJSON is the workspace.json file in a workspace storage directory.
+type workspaceJSON struct {
+	Folder        string `json:"folder,omitempty"`        // "file:///path/to/repo"
+	Configuration string `json:"configuration,omitempty"` // "file:///path/to/x.code-workspace"
+	Workspace     string `json:"workspace,omitempty"`     // older name for configuration
+}
+
+// codeWorkspaceFile is the subset of a .code-workspace file we read.
+type codeWorkspaceFile struct {
+	Folders []struct {
+		Path string `json:"path"`
+		URI  string `json:"uri"`
+	} `json:"folders"`
+}
+
+// workspaceMatch is a workspace storage directory whose workspace covers
+// the repo, together with the folders that workspace opened.
+type workspaceMatch struct {
+	Dir     string
+	Folders []string
+}
+
+// findWorkspaces returns the workspace storage directories under roots
+// whose workspace covers repoRoot, sorted by directory.
+func findWorkspaces(repoRoot string, roots []string) []workspaceMatch {
+	var matches []workspaceMatch
+	for _, baseDir := range roots {
+		entries, err := os.ReadDir(baseDir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+			dir := filepath.Join(baseDir, entry.Name())
+			folders := readWorkspaceFolders(filepath.Join(dir, "workspace.json"))
+			for _, folder := range folders {
+				if pathContains(folder, repoRoot) {
+					matches = append(matches, workspaceMatch{Dir: dir, Folders: folders})
+					break
+				}
+			}
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Dir < matches[j].Dir })
+	return matches
+}
+
+// readWorkspaceFolders returns the folders opened by the workspace a
+// workspace.json describes.
+func readWorkspaceFolders(wsPath string) []string {
+	data, err := os.ReadFile(wsPath)
+	if err != nil {
+		return nil
+	}
+	var ws workspaceJSON
+	if err := json.Unmarshal(data, &ws); err != nil {
+		return nil
+	}
+	if ws.Folder != "" {
+		if folder := uriToPath(ws.Folder); folder != "" {
+			return []string{filepath.Clean(folder)}
+		}
+		return nil
+	}
+
+	config := ws.Configuration
+	if config == "" {
+		config = ws.Workspace
+	}
+	if config == "" {
+		return nil
+	}
+	return readCodeWorkspaceFolders(uriToPath(config))
+}
+
+// readCodeWorkspaceFolders parses a .code-workspace file and returns its
+// folders as absolute paths. Relative paths are resolved against the
+// directory holding the file.
+func readCodeWorkspaceFolders(path string) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var cw codeWorkspaceFile
+	if err := json.Unmarshal(stripJSONC(data), &cw); err != nil {
+		return nil
+	}
+
+	var folders []string
+	for _, f := range cw.Folders {
+		var folder string
+		switch {
+		case f.URI != "":
+			folder = uriToPath(f.URI)
+		case f.Path != "":
+			folder = filepath.FromSlash(f.Path)
+			if !filepath.IsAbs(folder) {
+				folder = filepath.Join(filepath.Dir(path), folder)
+			}
+		}
+		if folder != "" {
+			folders = append(folders, filepath.Clean(folder))
+		}
+	}
+	return folders
+}
+
+// repoPath maps a file path recorded by the editor to a path relative to
+// repoRoot. Relative paths are taken against each workspace folder; in
+// multi-root workspaces they may also be prefixed with the folder name.
+// Reports false for paths outside the repo.
+func (m workspaceMatch) repoPath(p, repoRoot string) (string, bool) {
+	p = filepath.FromSlash(p)
+	if filepath.IsAbs(p) {
+		return repoRelPath(filepath.Clean(p), repoRoot)
+	}
+
+	// A leading folder name is the explicit multi-root form, so try it first
+	if len(m.Folders) > 1 {
+		first, rest, ok := strings.Cut(filepath.ToSlash(p), "/")
+		if ok {
+			for _, folder := range m.Folders {
+				if filepath.Base(folder) == first {
+					return repoRelPath(filepath.Join(folder, rest), repoRoot)
+				}
+			}
+		}
+	}
+	for _, folder := range m.Folders {
+		if rel, ok := repoRelPath(filepath.Join(folder, p), repoRoot); ok {
+			return rel, true
+		}
+	}
+	return "", false
+}
+
+// pathContains reports whether child is dir or lies beneath it.
+func pathContains(dir, child string) bool {
+	if dir == child {
+		return true
+	}
+	return strings.HasPrefix(child, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator))
+}
+
+// stripJSONC removes // and /* */ comments and trailing commas so a
+// .code-workspace file can be decoded with encoding/json.
+func stripJSONC(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	inString := false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if inString {
+			out = append(out, c)
+			if c == '\\' && i+1 < len(data) {
+				i++
+				out = append(out, data[i])
+			} else if c == '"' {
+				inString = false
+			}
+			continue
+		}
+		switch {
+		case c == '"':
+			inString = true
+			out = append(out, c)
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			if i < len(data) {
+				out = append(out, '\n')
+			}
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			i += 2
+			for i+1 < len(data) && (data[i] != '*' || data[i+1] != '/') {
+				i++
+			}
+			i++
+		case c == '}' || c == ']':
+			// Drop a trailing comma before the closing bracket
+			j := len(out) - 1
+			for j >= 0 && (out[j] == ' ' || out[j] == '\t' || out[j] == '\n' || out[j] == '\r') {
+				j--
+			}
+			if j >= 0 && out[j] == ',' {
+				out = append(out[:j], out[j+1:]...)
+			}
+			out = append(out, c)
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// mergeSessions folds src into dst, for a tool seen in several workspaces.
+// Either may be nil; the later model wins when both name one.
+func mergeSessions(dst, src *SessionInfo) *SessionInfo {
+	if src == nil {
+		return dst
+	}
+	if dst == nil {
+		return src
+	}
+	for f := range src.FilesWritten {
+		dst.FilesWritten[f] = struct{}{}
+	}
+	if src.Model != "" {
+		dst.Model = src.Model
+	}
+	dst.TotalTokens += src.TotalTokens
+	if src.SessionDurationSec > dst.SessionDurationSec {
+		dst.SessionDurationSec = src.SessionDurationSec
+	}
+	if src.LastActivity.After(dst.LastActivity) {
+		dst.LastActivity = src.LastActivity
+	}
+	dst.EditsAccepted += src.EditsAccepted
+	dst.EditsRejected += src.EditsRejected
+	return dst
+}
@@ -0,0 +1,173 @@
+package detector
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeWorkspaceJSON(t *testing.T, wsDir string, ws workspaceJSON) {
+	t.Helper()
+	if err := os.MkdirAll(wsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(ws)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(wsDir, "workspace.json"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFindWorkspaces_ParentFolder(t *testing.T) {
+	storage := t.TempDir()
+	repoRoot := "/Users/jose/projects/monorepo/api"
+
+	parentDir := filepath.Join(storage, "parent")
+	writeWorkspaceJSON(t, parentDir, workspaceJSON{Folder: "file:///Users/jose/projects/monorepo"})
+	exactDir := filepath.Join(storage, "exact")
+	writeWorkspaceJSON(t, exactDir, workspaceJSON{Folder: "file://" + repoRoot})
+	// A sibling whose name shares the repo's prefix must not match
+	writeWorkspaceJSON(t, filepath.Join(storage, "sibling"), workspaceJSON{Folder: "file:///Users/jose/projects/monorepo/ap"})
+	// Neither must a folder inside the repo
+	writeWorkspaceJSON(t, filepath.Join(storage, "child"), workspaceJSON{Folder: "file://" + repoRoot + "/cmd"})
+
+	got := findWorkspaces(repoRoot, []string{storage})
+	if len(got) != 2 {
+		t.Fatalf("got %+v, want 2 matches", got)
+	}
+	if got[0].Dir != exactDir || got[1].Dir != parentDir {
+		t.Errorf("dirs: got %q, %q", got[0].Dir, got[1].Dir)
+	}
+}
+
+func TestFindWorkspaces_CodeWorkspace(t *testing.T) {
+	storage := t.TempDir()
+	projects := t.TempDir()
+	repoRoot := filepath.Join(projects, "api")
+
+	wsFile := filepath.Join(projects, "dev.code-workspace")
+	content := `{
+	// Backend and frontend side by side
+	"folders": [
+		{"path": "api"},
+		{"uri": "file://` + filepath.Join(projects, "web") + `"}, /* absolute */
+	],
+	"settings": {"url": "http://example.com"},
+}`
+	if err := os.WriteFile(wsFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	wsDir := filepath.Join(storage, "multi")
+	writeWorkspaceJSON(t, wsDir, workspaceJSON{Configuration: "file://" + wsFile})
+	legacyDir := filepath.Join(storage, "legacy")
+	writeWorkspaceJSON(t, legacyDir, workspaceJSON{Workspace: "file://" + wsFile})
+
+	got := findWorkspaces(repoRoot, []string{storage})
+	if len(got) != 2 || got[0].Dir != legacyDir || got[1].Dir != wsDir {
+		t.Fatalf("got %+v", got)
+	}
+	wantFolders := []string{repoRoot, filepath.Join(projects, "web")}
+	if !equal(got[1].Folders, wantFolders) {
+		t.Errorf("folders: got %v, want %v", got[1].Folders, wantFolders)
+	}
+}
+
+func TestWorkspaceMatch_RepoPath(t *testing.T) {
+	repoRoot := "/work/monorepo/api"
+
+	parent := workspaceMatch{Folders: []string{"/work/monorepo"}}
+	multi := workspaceMatch{Folders: []string{"/work/monorepo/api", "/work/monorepo/web"}}
+	exact := workspaceMatch{Folders: []string{repoRoot}}
+
+	tests := []struct {
+		name   string
+		ws     workspaceMatch
+		path   string
+		want   string
+		wantOK bool
+	}{
+		{"exact relative", exact, "src/main.go", "src/main.go", true},
+		{"exact escapes repo", exact, "../web/app.ts", "", false},
+		{"parent rebased", parent, "api/src/main.go", "src/main.go", true},
+		{"parent outside repo", parent, "web/app.ts", "", false},
+		{"absolute inside", parent, "/work/monorepo/api/go.mod", "go.mod", true},
+		{"absolute outside", exact, "/etc/hosts", "", false},
+		{"multi-root folder prefix", multi, "api/src/main.go", "src/main.go", true},
+		{"multi-root other folder", multi, "web/app.ts", "", false},
+		{"multi-root bare", multi, "src/main.go", "src/main.go", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.ws.repoPath(tt.path, repoRoot)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("repoPath(%q) = %q, %v; want %q, %v", tt.path, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestDetectCursor_ParentFolder(t *testing.T) {
+	skipIfNoSQLite(t)
+	homeDir := t.TempDir()
+	t.Setenv("HOME", homeDir)
+
+	repoRoot := "/Users/jose/projects/monorepo/api"
+
+	wsDir := filepath.Join(testCursorWorkspaceStorage(homeDir), "abc123")
+	writeWorkspaceJSON(t, wsDir, workspaceJSON{Folder: "file:///Users/jose/projects/monorepo"})
+
+	now := time.Now().UnixMilli()
+	composerId := "parent-composer-1"
+	indexData, err := json.Marshal(cursorComposerIndex{
+		AllComposers: []cursorComposerHead{
+			{ComposerID: composerId, LastUpdatedAt: now, CreatedAt: now - 60000, UnifiedMode: "agent"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	createTestDB(t, filepath.Join(wsDir, "state.vscdb"), []string{
+		`CREATE TABLE ItemTable (key TEXT UNIQUE ON CONFLICT REPLACE, value BLOB);`,
+		fmt.Sprintf(`INSERT INTO ItemTable (key, value) VALUES ('composer.composerData', '%s');`,
+			escapeSQLString(string(indexData))),
+	})
+
+	globalDir := testCursorGlobalStorage(homeDir)
+	if err := os.MkdirAll(globalDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	stmts := []string{`CREATE TABLE cursorDiskKV (key TEXT UNIQUE ON CONFLICT REPLACE, value BLOB);`}
+	for i, path := range []string{"api/src/main.go", "web/app.ts"} {
+		bubbleData, err := json.Marshal(cursorBubble{
+			Type: 2,
+			ToolFormerData: &cursorToolFormer{
+				Name:   "edit_file",
+				Status: "completed",
+				Params: fmt.Sprintf(`{"relativeWorkspacePath":%q}`, path),
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		stmts = append(stmts, fmt.Sprintf(`INSERT INTO cursorDiskKV (key, value) VALUES ('bubbleId:%s:bubble-%d', '%s');`,
+			composerId, i, escapeSQLString(string(bubbleData))))
+	}
+	createTestDB(t, filepath.Join(globalDir, "state.vscdb"), stmts)
+
+	info, err := detectCursor(repoRoot, cursorBaseDirs(), 72*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info == nil {
+		t.Fatal("expected non-nil info")
+	}
+	if got := sortedKeys(info.FilesWritten); !equal(got, []string{"src/main.go"}) {
+		t.Errorf("files: got %v, want [src/main.go]", got)
+	}
+}
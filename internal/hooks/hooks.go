@@ -3,6 +3,7 @@ package hooks
 import (
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 )
@@ -18,10 +19,27 @@ fi
 
 const prePushHook = `# --- TEMPO CLI HOOK ---
 if command -v tempo-cli >/dev/null 2>&1; then
-  tempo-cli _sync
+  tempo-cli _sync "$1"
 fi
 # --- END TEMPO CLI HOOK ---`
 
+const prepareCommitMsgHook = `# --- TEMPO CLI HOOK ---
+if command -v tempo-cli >/dev/null 2>&1; then
+  tempo-cli _prepare-commit-msg "$1" "$2" "$3"
+fi
+# --- END TEMPO CLI HOOK ---`
+
+// Trailer modes for the prepare-commit-msg hook, stored per repo in the
+// tempo.trailers git config key.
+const (
+	TrailersOff     = "off"
+	TrailersOn      = "on"
+	TrailersPreview = "preview"
+)
+
+// trailerConfigKey is the per-repo git config key holding the trailer mode.
+const trailerConfigKey = "tempo.trailers"
+
 // Install installs post-commit and pre-push hooks in the given repo.
 func Install(repoRoot string) error {
 	hooksDir := filepath.Join(repoRoot, ".git", "hooks")
@@ -43,14 +61,141 @@ func Install(repoRoot string) error {
 	return ensureGitignore(repoRoot)
 }
 
-// Uninstall removes Tempo's hook sections from post-commit and pre-push.
+// Uninstall removes Tempo's hook sections from post-commit, pre-push and
+// prepare-commit-msg, and turns disclosure trailers off.
 func Uninstall(repoRoot string) error {
 	hooksDir := filepath.Join(repoRoot, ".git", "hooks")
-	for _, name := range []string{"post-commit", "pre-push"} {
+	for _, name := range []string{"post-commit", "pre-push", "prepare-commit-msg"} {
 		if err := removeHookSection(hooksDir, name); err != nil {
 			return err
 		}
 	}
+	if TrailerMode(repoRoot) != TrailersOff {
+		return gitRun(repoRoot, "config", "--unset", trailerConfigKey)
+	}
+	return nil
+}
+
+// EnableTrailers installs the prepare-commit-msg hook and records the
+// trailer mode (TrailersOn or TrailersPreview) in the repo's git config.
+func EnableTrailers(repoRoot, mode string) error {
+	if mode != TrailersOn && mode != TrailersPreview {
+		return fmt.Errorf("invalid trailer mode %q (want %q or %q)", mode, TrailersOn, TrailersPreview)
+	}
+	hooksDir := filepath.Join(repoRoot, ".git", "hooks")
+	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+		return err
+	}
+	if err := installHook(hooksDir, "prepare-commit-msg", prepareCommitMsgHook); err != nil {
+		return fmt.Errorf("prepare-commit-msg: %w", err)
+	}
+	return gitRun(repoRoot, "config", trailerConfigKey, mode)
+}
+
+// TrailerMode returns the repo's disclosure trailer mode. Trailers are off
+// unless the repo opted in.
+func TrailerMode(repoRoot string) string {
+	cmd := exec.Command("git", "config", "--get", trailerConfigKey)
+	cmd.Dir = repoRoot
+	out, err := cmd.Output()
+	if err != nil {
+		return TrailersOff
+	}
+	switch strings.ToLower(strings.TrimSpace(string(out))) {
+	case "on", "true", "yes", "1":
+		return TrailersOn
+	case "preview":
+		return TrailersPreview
+	}
+	return TrailersOff
+}
+
+// AddTrailers adds trailers to a commit message file, replacing any
+// disclosure trailers already there so the message reflects the current
+// detection. Only lines git parses as trailers are replaced; body text that
+// happens to start with key is kept. With no trailers to add, the file is
+// left alone. git interpret-trailers places them after existing trailers
+// and before the comment block.
+func AddTrailers(msgFile, key string, trailers []string) error {
+	if len(trailers) == 0 {
+		return nil
+	}
+	out, err := exec.Command("git", "interpret-trailers", "--parse", msgFile).Output()
+	if err != nil {
+		return fmt.Errorf("git interpret-trailers --parse: %w", err)
+	}
+	if hasTrailerKey(string(out), key) {
+		data, err := os.ReadFile(msgFile)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(msgFile, []byte(removeTrailers(string(data), key)), 0644); err != nil {
+			return err
+		}
+	}
+
+	args := []string{"interpret-trailers", "--in-place"}
+	for _, t := range trailers {
+		args = append(args, "--trailer", t)
+	}
+	args = append(args, msgFile)
+	if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("git interpret-trailers: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// removeTrailers removes the trailers with key from the trailer block of
+// msg: its last paragraph, before the comment block and any scissors line,
+// which is where git reads trailers from.
+func removeTrailers(msg, key string) string {
+	lines := strings.Split(msg, "\n")
+	end := len(lines)
+	for i, line := range lines {
+		if strings.HasPrefix(line, "# ") && strings.Contains(line, ">8") {
+			end = i
+			break
+		}
+	}
+	for end > 0 && (strings.TrimSpace(lines[end-1]) == "" || strings.HasPrefix(lines[end-1], "#")) {
+		end--
+	}
+	start := end
+	for start > 0 && strings.TrimSpace(lines[start-1]) != "" {
+		start--
+	}
+	var kept []string
+	for i, line := range lines {
+		if i >= start && i < end && hasTrailerKey(line, key) {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	if start > 0 && len(kept) == len(lines)-(end-start) {
+		// The block held only these trailers; drop the blank line before
+		// it too, or the new block would follow two
+		kept = append(kept[:start-1:start-1], kept[start:]...)
+	}
+	return strings.Join(kept, "\n")
+}
+
+// hasTrailerKey reports whether any line of text is a trailer with key.
+func hasTrailerKey(text, key string) bool {
+	for _, line := range strings.Split(text, "\n") {
+		k, _, ok := strings.Cut(line, ":")
+		if ok && strings.EqualFold(strings.TrimSpace(k), key) {
+			return true
+		}
+	}
+	return false
+}
+
+func gitRun(repoRoot string, args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = repoRoot
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
 	return nil
 }
 
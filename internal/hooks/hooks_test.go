@@ -2,6 +2,7 @@ package hooks
 
 import (
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -280,3 +281,107 @@ func TestEnsureGitignore_Idempotent(t *testing.T) {
 		t.Error(".tempo/ should appear exactly once")
 	}
 }
+
+func setupGitRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	cmd := exec.Command("git", "init", "-q")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	return dir
+}
+
+func TestEnableTrailers(t *testing.T) {
+	repo := setupGitRepo(t)
+
+	if got := TrailerMode(repo); got != TrailersOff {
+		t.Errorf("default mode: got %q, want %q", got, TrailersOff)
+	}
+
+	if err := EnableTrailers(repo, TrailersPreview); err != nil {
+		t.Fatal(err)
+	}
+	if got := TrailerMode(repo); got != TrailersPreview {
+		t.Errorf("mode: got %q, want %q", got, TrailersPreview)
+	}
+	data, err := os.ReadFile(filepath.Join(repo, ".git", "hooks", "prepare-commit-msg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "tempo-cli _prepare-commit-msg") {
+		t.Error("missing prepare-commit-msg command")
+	}
+
+	if err := EnableTrailers(repo, "sometimes"); err == nil {
+		t.Error("expected error for invalid mode")
+	}
+
+	if err := Uninstall(repo); err != nil {
+		t.Fatal(err)
+	}
+	if got := TrailerMode(repo); got != TrailersOff {
+		t.Errorf("mode after uninstall: got %q, want %q", got, TrailersOff)
+	}
+	if _, err := os.Stat(filepath.Join(repo, ".git", "hooks", "prepare-commit-msg")); !os.IsNotExist(err) {
+		t.Error("prepare-commit-msg hook should be removed")
+	}
+}
+
+func TestAddTrailers(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	msgFile := filepath.Join(t.TempDir(), "COMMIT_EDITMSG")
+	msg := "Add parser\n\nSigned-off-by: Jane <jane@example.com>\nAI-Assisted-By: cursor\n\n# Please enter the commit message for your changes.\n"
+	if err := os.WriteFile(msgFile, []byte(msg), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	trailers := []string{"AI-Assisted-By: claude-code (claude-opus-4-6); files=2/5"}
+	if err := AddTrailers(msgFile, "AI-Assisted-By", trailers); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(msgFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+	want := "Add parser\n\nSigned-off-by: Jane <jane@example.com>\nAI-Assisted-By: claude-code (claude-opus-4-6); files=2/5\n\n# Please enter the commit message for your changes.\n"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestAddTrailers_KeepsBodyAndExisting(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	msgFile := filepath.Join(t.TempDir(), "COMMIT_EDITMSG")
+	msg := "Document trailers\n\nAI-Assisted-By: is the trailer we add.\nIt names each tool.\n\nAI-Assisted-By: cursor\nAI-Assisted-By: aider\n"
+	if err := os.WriteFile(msgFile, []byte(msg), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing detected: the message is left as it is
+	if err := AddTrailers(msgFile, "AI-Assisted-By", nil); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(msgFile); string(data) != msg {
+		t.Errorf("got:\n%s\nwant it unchanged", data)
+	}
+
+	if err := AddTrailers(msgFile, "AI-Assisted-By", []string{"AI-Assisted-By: claude-code; files=1/1"}); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(msgFile)
+	want := "Document trailers\n\nAI-Assisted-By: is the trailer we add.\nIt names each tool.\n\nAI-Assisted-By: claude-code; files=1/1\n"
+	if string(data) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", data, want)
+	}
+}
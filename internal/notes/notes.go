@@ -0,0 +1,180 @@
+package notes
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/usetempo/tempo-cli/internal/detector"
+)
+
+// Ref is the notes ref attributions are stored under.
+const Ref = "refs/notes/tempo"
+
+// remoteRef receives the remote's notes during Fetch before they are merged.
+const remoteRef = "refs/notes/tempo-remote"
+
+// configKey is the per-repo git config key that opts into notes.
+const configKey = "tempo.notes"
+
+// ErrNoNote is returned by Read when a commit has no Tempo note.
+var ErrNoNote = errors.New("no tempo note for commit")
+
+// Each note holds one compact JSON attribution per line. Line-based notes
+// survive git's "concatenate" rewrite mode (rebase, amend) and the
+// "cat_sort_uniq" merge strategy used by Fetch without becoming invalid.
+
+// Enabled reports whether the repo opted into writing notes.
+func Enabled(repoRoot string) bool {
+	out, err := git(repoRoot, "config", "--bool", "--get", configKey)
+	return err == nil && strings.TrimSpace(out) == "true"
+}
+
+// Enable opts the repo into notes and configures notes.rewriteRef so notes
+// follow commits through rebase and amend.
+func Enable(repoRoot string) error {
+	if _, err := git(repoRoot, "config", configKey, "true"); err != nil {
+		return err
+	}
+	out, _ := git(repoRoot, "config", "--get-all", "notes.rewriteRef")
+	for _, ref := range strings.Fields(out) {
+		if ref == Ref {
+			return nil
+		}
+	}
+	_, err := git(repoRoot, "config", "--add", "notes.rewriteRef", Ref)
+	return err
+}
+
+// Disable stops writing notes and removes the rewriteRef entry. Existing
+// notes are kept.
+func Disable(repoRoot string) error {
+	if !Enabled(repoRoot) {
+		return nil
+	}
+	if _, err := git(repoRoot, "config", "--unset", configKey); err != nil {
+		return err
+	}
+	// Exit status 5 means the entry was already gone
+	git(repoRoot, "config", "--unset", "notes.rewriteRef", "^"+regexp.QuoteMeta(Ref)+"$")
+	return nil
+}
+
+// Write stores attr as the Tempo note on its commit, replacing any
+// existing note.
+func Write(repoRoot string, attr *detector.Attribution) error {
+	if attr.CommitSHA == "" {
+		return fmt.Errorf("attribution has no commit SHA")
+	}
+	data, err := json.Marshal(attr)
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command("git", "notes", "--ref", Ref, "add", "-f", "-F", "-", attr.CommitSHA)
+	cmd.Dir = repoRoot
+	cmd.Stdin = strings.NewReader(string(data) + "\n")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git notes add: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Read returns the attribution noted on rev. A note that gathered several
+// attributions (e.g. copied by a rebase onto a commit that already had
+// one) yields the one recorded for this commit, or else their detections
+// merged.
+func Read(repoRoot, rev string) (*detector.Attribution, error) {
+	sha, err := git(repoRoot, "rev-parse", "--verify", rev+"^{commit}")
+	if err != nil {
+		return nil, fmt.Errorf("unknown revision %q", rev)
+	}
+	sha = strings.TrimSpace(sha)
+
+	out, err := git(repoRoot, "notes", "--ref", Ref, "show", sha)
+	if err != nil {
+		return nil, ErrNoNote
+	}
+	return parseNote(out, sha)
+}
+
+func parseNote(note, sha string) (*detector.Attribution, error) {
+	var attrs []*detector.Attribution
+	scanner := bufio.NewScanner(strings.NewReader(note))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var attr detector.Attribution
+		if err := json.Unmarshal([]byte(line), &attr); err != nil {
+			continue
+		}
+		if attr.CommitSHA == sha {
+			return &attr, nil
+		}
+		attrs = append(attrs, &attr)
+	}
+	if len(attrs) == 0 {
+		return nil, ErrNoNote
+	}
+
+	merged := *attrs[0]
+	merged.CommitSHA = sha
+	merged.Detections = nil
+	for _, a := range attrs {
+		merged.Detections = append(merged.Detections, a.Detections...)
+	}
+	return &merged, nil
+}
+
+// Fetch fetches the remote's notes and merges them into the local ref,
+// keeping notes from both sides.
+func Fetch(repoRoot, remote string) error {
+	if _, err := git(repoRoot, "fetch", "--quiet", remote, "+"+Ref+":"+remoteRef); err != nil {
+		return err
+	}
+	if _, err := git(repoRoot, "rev-parse", "--verify", "--quiet", Ref); err != nil {
+		_, err := git(repoRoot, "update-ref", Ref, remoteRef)
+		return err
+	}
+	_, err := git(repoRoot, "notes", "--ref", Ref, "merge", "--quiet", "-s", "cat_sort_uniq", remoteRef)
+	return err
+}
+
+// Push pushes the local notes ref to remote. It skips hooks so that it can
+// run from pre-push without recursing. If the remote has notes we don't,
+// they are fetched and merged first, then the push is retried once.
+func Push(repoRoot, remote string) error {
+	if _, err := git(repoRoot, "rev-parse", "--verify", "--quiet", Ref); err != nil {
+		return nil
+	}
+	push := func() error {
+		_, err := git(repoRoot, "push", "--quiet", "--no-verify", remote, Ref+":"+Ref)
+		return err
+	}
+	if err := push(); err == nil {
+		return nil
+	}
+	if err := Fetch(repoRoot, remote); err != nil {
+		return err
+	}
+	return push()
+}
+
+func git(repoRoot string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = repoRoot
+	out, err := cmd.Output()
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok && len(ee.Stderr) > 0 {
+			return string(out), fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(string(ee.Stderr)))
+		}
+		return string(out), fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return string(out), nil
+}
@@ -0,0 +1,211 @@
+package notes
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/usetempo/tempo-cli/internal/detector"
+)
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=Jane", "GIT_AUTHOR_EMAIL=jane@example.com",
+		"GIT_COMMITTER_NAME=Jane", "GIT_COMMITTER_EMAIL=jane@example.com")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func setupRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	runGit(t, dir, "init", "-q")
+	runGit(t, dir, "config", "user.name", "Jane")
+	runGit(t, dir, "config", "user.email", "jane@example.com")
+	commit(t, dir, "a.go")
+	return dir
+}
+
+func commit(t *testing.T, dir, name string) string {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, dir, "add", name)
+	runGit(t, dir, "commit", "-q", "-m", "add "+name)
+	return runGit(t, dir, "rev-parse", "HEAD")
+}
+
+func testAttribution(sha string, tool detector.Tool) *detector.Attribution {
+	return &detector.Attribution{
+		CommitSHA:    sha,
+		CommitAuthor: "jane@example.com",
+		Detections: []detector.Detection{
+			{Tool: tool, Confidence: detector.ConfidenceHigh, Method: detector.MethodFileMatch, FilesMatched: []string{"a.go"}, FilesCommitted: 1, AIFiles: 1},
+		},
+	}
+}
+
+func TestEnableDisable(t *testing.T) {
+	repo := setupRepo(t)
+	if Enabled(repo) {
+		t.Fatal("notes should be off by default")
+	}
+	if err := Enable(repo); err != nil {
+		t.Fatal(err)
+	}
+	if err := Enable(repo); err != nil {
+		t.Fatal(err)
+	}
+	if !Enabled(repo) {
+		t.Error("expected notes enabled")
+	}
+	if got := runGit(t, repo, "config", "--get-all", "notes.rewriteRef"); got != Ref {
+		t.Errorf("rewriteRef: got %q, want a single %q", got, Ref)
+	}
+
+	if err := Disable(repo); err != nil {
+		t.Fatal(err)
+	}
+	if Enabled(repo) {
+		t.Error("expected notes disabled")
+	}
+	cmd := exec.Command("git", "config", "--get-all", "notes.rewriteRef")
+	cmd.Dir = repo
+	if out, _ := cmd.Output(); len(out) != 0 {
+		t.Errorf("rewriteRef still set: %q", out)
+	}
+}
+
+func TestWriteRead(t *testing.T) {
+	repo := setupRepo(t)
+	sha := runGit(t, repo, "rev-parse", "HEAD")
+
+	if _, err := Read(repo, "HEAD"); err != ErrNoNote {
+		t.Fatalf("expected ErrNoNote, got %v", err)
+	}
+
+	if err := Write(repo, testAttribution(sha, detector.ToolClaudeCode)); err != nil {
+		t.Fatal(err)
+	}
+	// Writing again replaces the note
+	if err := Write(repo, testAttribution(sha, detector.ToolCursor)); err != nil {
+		t.Fatal(err)
+	}
+
+	attr, err := Read(repo, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attr.CommitSHA != sha || len(attr.Detections) != 1 || attr.Detections[0].Tool != detector.ToolCursor {
+		t.Errorf("got %+v", attr)
+	}
+}
+
+func TestNotesSurviveAmend(t *testing.T) {
+	repo := setupRepo(t)
+	if err := Enable(repo); err != nil {
+		t.Fatal(err)
+	}
+	sha := runGit(t, repo, "rev-parse", "HEAD")
+	if err := Write(repo, testAttribution(sha, detector.ToolClaudeCode)); err != nil {
+		t.Fatal(err)
+	}
+
+	runGit(t, repo, "commit", "-q", "--amend", "-m", "reworded")
+	attr, err := Read(repo, "HEAD")
+	if err != nil {
+		t.Fatalf("note lost on amend: %v", err)
+	}
+	if len(attr.Detections) != 1 || attr.Detections[0].Tool != detector.ToolClaudeCode {
+		t.Errorf("got %+v", attr)
+	}
+}
+
+func TestParseNote(t *testing.T) {
+	note := `{"commit_sha":"old","commit_author":"a","repo":"","timestamp":"","detections":[{"tool":"cursor","confidence":"high","method":"file-match","files_committed":1}]}
+
+{"commit_sha":"other","commit_author":"a","repo":"","timestamp":"","detections":[{"tool":"codex","confidence":"high","method":"file-match","files_committed":1}]}
+`
+	attr, err := parseNote(note, "new")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attr.CommitSHA != "new" || len(attr.Detections) != 2 {
+		t.Errorf("merged: got %+v", attr)
+	}
+
+	attr, err = parseNote(note, "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attr.Detections) != 1 || attr.Detections[0].Tool != detector.ToolCodex {
+		t.Errorf("exact: got %+v", attr)
+	}
+
+	if _, err := parseNote("not json\n", "x"); err != ErrNoNote {
+		t.Errorf("expected ErrNoNote, got %v", err)
+	}
+}
+
+func TestPushFetch(t *testing.T) {
+	repo := setupRepo(t)
+	remote := t.TempDir()
+	runGit(t, remote, "init", "-q", "--bare")
+	runGit(t, repo, "remote", "add", "origin", remote)
+	runGit(t, repo, "push", "-q", "origin", "HEAD")
+
+	first := runGit(t, repo, "rev-parse", "HEAD")
+	if err := Write(repo, testAttribution(first, detector.ToolClaudeCode)); err != nil {
+		t.Fatal(err)
+	}
+	if err := Push(repo, "origin"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A teammate clones, adds a note of their own and pushes
+	clone := t.TempDir()
+	runGit(t, clone, "clone", "-q", remote, ".")
+	runGit(t, clone, "config", "user.name", "Sam")
+	runGit(t, clone, "config", "user.email", "sam@example.com")
+	if err := Fetch(clone, "origin"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Read(clone, first); err != nil {
+		t.Fatalf("teammate can't read note: %v", err)
+	}
+	second := commit(t, clone, "b.go")
+	if err := Write(clone, testAttribution(second, detector.ToolCodex)); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, clone, "push", "-q", "origin", "HEAD")
+	if err := Push(clone, "origin"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Meanwhile we noted another commit; pushing merges theirs in first
+	runGit(t, repo, "pull", "-q", "--ff-only", "origin", "HEAD")
+	third := commit(t, repo, "c.go")
+	if err := Write(repo, testAttribution(third, detector.ToolCursor)); err != nil {
+		t.Fatal(err)
+	}
+	if err := Push(repo, "origin"); err != nil {
+		t.Fatal(err)
+	}
+	for _, sha := range []string{first, second, third} {
+		if _, err := Read(repo, sha); err != nil {
+			t.Errorf("note on %s: %v", sha, err)
+		}
+	}
+}
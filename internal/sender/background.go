@@ -0,0 +1,63 @@
+package sender
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// maxLogSize is the size past which the sync log is rotated to
+// sync.log.1 before a background sync appends to it.
+const maxLogSize = 1 << 20
+
+func syncLockPath(repoRoot string) string {
+	return filepath.Join(repoRoot, ".tempo", "sync.lock")
+}
+
+// SyncLogPath returns the log that background syncs append their output
+// to.
+func SyncLogPath(repoRoot string) string {
+	return filepath.Join(repoRoot, ".tempo", "sync.log")
+}
+
+// StartBackground runs this executable with args in repoRoot as a
+// detached process, its output appended to the sync log, and returns
+// without waiting for it. The process keeps running after the caller, a
+// git hook, exits.
+func StartBackground(repoRoot string, args ...string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	logFile, err := openSyncLog(repoRoot)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+	fmt.Fprintf(logFile, "--- %s sync started\n", time.Now().Format(time.RFC3339))
+
+	cmd := exec.Command(exe, args...)
+	cmd.Dir = repoRoot
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	cmd.SysProcAttr = detachAttr()
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	return cmd.Process.Release()
+}
+
+// openSyncLog opens the sync log for appending, rotating it first if it
+// has grown past maxLogSize.
+func openSyncLog(repoRoot string) (*os.File, error) {
+	path := SyncLogPath(repoRoot)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	if st, err := os.Stat(path); err == nil && st.Size() > maxLogSize {
+		os.Rename(path, path+".1")
+	}
+	return os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+}
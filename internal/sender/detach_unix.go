@@ -0,0 +1,11 @@
+//go:build !windows
+
+package sender
+
+import "syscall"
+
+// detachAttr starts the process in a new session, so it has no
+// controlling terminal and outlives the hook that started it.
+func detachAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
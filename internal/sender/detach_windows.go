@@ -0,0 +1,16 @@
+//go:build windows
+
+package sender
+
+import "syscall"
+
+const (
+	createNewProcessGroup = 0x00000200
+	detachedProcess       = 0x00000008
+)
+
+// detachAttr starts the process without a console in its own process
+// group, so it outlives the hook that started it and ignores Ctrl+C.
+func detachAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{CreationFlags: createNewProcessGroup | detachedProcess, HideWindow: true}
+}
@@ -0,0 +1,134 @@
+package sender
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/usetempo/tempo-cli/internal/detector"
+)
+
+// Failure is a record the API rejected permanently, moved out of the
+// pending queue into .tempo/failed/ so it no longer blocks sync.
+type Failure struct {
+	// Name is the record's file name, the same in both directories.
+	Name string `json:"-"`
+
+	Version  int                   `json:"version"`
+	Kind     Kind                  `json:"kind"`
+	Error    string                `json:"error"`
+	Status   int                   `json:"status,omitempty"`
+	FailedAt string                `json:"failed_at"`
+	Record   *detector.Attribution `json:"record"`
+}
+
+func failedDir(repoRoot string) string {
+	return filepath.Join(repoRoot, ".tempo", "failed")
+}
+
+// quarantine moves e's record to .tempo/failed/ with the API's error,
+// unless it was replaced since it was sent; the newer record stays
+// queued.
+func (s *Store) quarantine(e *Entry, status int, message string) error {
+	return s.withLock(false, func() error {
+		if !s.unchanged(e) {
+			return nil
+		}
+		if err := os.MkdirAll(failedDir(s.repoRoot), 0755); err != nil {
+			return err
+		}
+		f := &Failure{
+			Version:  storeVersion,
+			Kind:     e.Kind,
+			Error:    message,
+			Status:   status,
+			FailedAt: time.Now().UTC().Format(time.RFC3339),
+			Record:   e.Record,
+		}
+		if err := writeJSONFile(filepath.Join(failedDir(s.repoRoot), e.name()), f); err != nil {
+			return err
+		}
+		return os.Remove(filepath.Join(s.dir(), e.name()))
+	})
+}
+
+// Failed returns the quarantined records, oldest first.
+func (s *Store) Failed() ([]*Failure, error) {
+	var failures []*Failure
+	err := s.withLock(false, func() error {
+		dirEntries, err := os.ReadDir(failedDir(s.repoRoot))
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		for _, de := range dirEntries {
+			if !isRecordFile(de) {
+				continue
+			}
+			data, err := os.ReadFile(filepath.Join(failedDir(s.repoRoot), de.Name()))
+			if err != nil {
+				return err
+			}
+			var f Failure
+			if err := json.Unmarshal(data, &f); err != nil || f.Record == nil {
+				return fmt.Errorf("%s: not a failed record", de.Name())
+			}
+			if f.Version > storeVersion {
+				return fmt.Errorf("%s: format version %d: %w", de.Name(), f.Version, errNewerVersion)
+			}
+			f.Name = de.Name()
+			if f.Kind == "" {
+				// Quarantined before failures recorded their kind
+				f.Kind = KindAttribution
+				if strings.HasPrefix(f.Name, string(KindCorrection)+"-") {
+					f.Kind = KindCorrection
+				}
+			}
+			failures = append(failures, &f)
+		}
+		return nil
+	})
+	return failures, err
+}
+
+// FailedCount returns the number of quarantined records.
+func (s *Store) FailedCount() int {
+	dirEntries, err := os.ReadDir(failedDir(s.repoRoot))
+	if err != nil {
+		return 0
+	}
+	count := 0
+	for _, de := range dirEntries {
+		if isRecordFile(de) {
+			count++
+		}
+	}
+	return count
+}
+
+// Retry moves a quarantined record back to the queue, to be sent again on
+// the next sync. If a newer record was queued for the commit since, that
+// one is kept and the quarantined one dropped.
+func (s *Store) Retry(f *Failure) error {
+	return s.withLock(true, func() error {
+		e := &Entry{Kind: f.Kind, QueuedAt: time.Now().UTC(), Record: f.Record}
+		if _, err := s.read(e.name()); os.IsNotExist(err) {
+			if err := s.write(e); err != nil {
+				return err
+			}
+		}
+		return os.Remove(filepath.Join(failedDir(s.repoRoot), f.Name))
+	})
+}
+
+// Drop deletes a quarantined record.
+func (s *Store) Drop(f *Failure) error {
+	return s.withLock(true, func() error {
+		return os.Remove(filepath.Join(failedDir(s.repoRoot), f.Name))
+	})
+}
@@ -0,0 +1,25 @@
+package sender
+
+import (
+	"errors"
+	"os"
+)
+
+// errLocked is returned by lockFile when another process holds the lock.
+var errLocked = errors.New("locked by another process")
+
+// lockFile opens path, creating it if needed, and takes an exclusive
+// advisory lock on it. With wait false it returns errLocked instead of
+// blocking. Closing the file releases the lock, and so does the process
+// exiting, so a crashed holder never leaves it stuck.
+func lockFile(path string, wait bool) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := flock(f, wait); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
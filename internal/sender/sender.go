@@ -1,132 +1,245 @@
 package sender
 
 import (
-	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
-	"path/filepath"
-	"strings"
 	"time"
 
 	"github.com/usetempo/tempo-cli/internal/config"
 	"github.com/usetempo/tempo-cli/internal/detector"
 )
 
-// SavePending atomically writes an attribution to .tempo/pending/.
-func SavePending(repoRoot string, attr *detector.Attribution) error {
-	dir := filepath.Join(repoRoot, ".tempo", "pending")
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return err
-	}
+// recordID returns the stable ID of a record: its commit SHA and a hash of
+// its content. The detection timestamp is left out, so recording the same
+// commit again yields the same ID and the API can drop the repeat.
+func recordID(attr *detector.Attribution) string {
+	c := *attr
+	c.ID = ""
+	c.Timestamp = ""
+	data, _ := json.Marshal(&c)
+	sum := sha256.Sum256(data)
+	return c.CommitSHA + "-" + hex.EncodeToString(sum[:8])
+}
 
-	data, err := json.MarshalIndent(attr, "", "  ")
+// Sync sends the records in the pending store to the API of the profile
+// that applies to the repo, oldest first, in batches. It holds the sync
+// lock while sending and returns at once if another sync holds it. Each
+// record is removed from the store once the API accepts it, so an
+// interrupted sync keeps its progress; the rest stay queued for the next
+// sync. If the profile has no API token, silently returns nil (offline
+// mode).
+func Sync(repoRoot string, version string) error {
+	cfg, err := config.Load(repoRoot)
 	if err != nil {
-		return err
-	}
-
-	filename := fmt.Sprintf("%d.json", time.Now().UnixMilli())
-	tmpPath := filepath.Join(dir, ".tmp-"+filename)
-	finalPath := filepath.Join(dir, filename)
-
-	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
-		return err
+		fmt.Fprintf(os.Stderr, "tempo-cli: warning: %v, keeping pending files\n", err)
+		return nil
 	}
-	return os.Rename(tmpPath, finalPath)
-}
-
-// PendingCount returns the number of pending attribution files.
-func PendingCount(repoRoot string) int {
-	dir := filepath.Join(repoRoot, ".tempo", "pending")
-	entries, err := os.ReadDir(dir)
+	_, profile := cfg.ResolveProfile(detector.RemoteHostPath(repoRoot))
+	token, err := profile.Token()
 	if err != nil {
-		return 0
+		fmt.Fprintf(os.Stderr, "tempo-cli: warning: reading API token: %v, keeping pending files\n", err)
+		return nil
 	}
-	count := 0
-	for _, e := range entries {
-		if !e.IsDir() && strings.HasSuffix(e.Name(), ".json") && !strings.HasPrefix(e.Name(), ".tmp-") {
-			count++
-		}
+	if token == "" {
+		return nil
 	}
-	return count
-}
 
-// Sync reads all pending attributions and sends them to the API.
-// On success, deletes the sent files. On failure, keeps them.
-// If no API token, silently returns nil (offline mode).
-func Sync(repoRoot string, version string) error {
-	cfg, err := config.Load()
-	if err != nil {
+	store := OpenStore(repoRoot)
+	if store.Count() == 0 {
 		return nil
 	}
-	if cfg.APIToken == "" {
+
+	// Only one sync sends at a time, so overlapping pushes don't send the
+	// same records twice
+	lock, err := lockFile(syncLockPath(repoRoot), false)
+	if err == errLocked {
+		fmt.Fprintln(os.Stderr, "tempo-cli: another sync is running, leaving pending files to it")
 		return nil
 	}
-
-	dir := filepath.Join(repoRoot, ".tempo", "pending")
-	entries, err := os.ReadDir(dir)
 	if err != nil {
+		fmt.Fprintf(os.Stderr, "tempo-cli: warning: locking %s: %v, keeping pending files\n", syncLockPath(repoRoot), err)
 		return nil
 	}
+	defer lock.Close()
 
-	var attributions []*detector.Attribution
-	var filePaths []string
-
-	for _, entry := range entries {
-		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
-			continue
-		}
-		if strings.HasPrefix(entry.Name(), ".tmp-") {
-			continue
-		}
-		path := filepath.Join(dir, entry.Name())
-		data, err := os.ReadFile(path)
+	entries, err := store.List()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "tempo-cli: warning: reading pending records: %v\n", err)
+		return nil
+	}
+	attributions := &queue{path: "/v1/attributions", key: "attributions"}
+	corrections := &queue{path: "/v1/attributions/corrections", key: "corrections", notFoundRefuses: true}
+	for _, e := range entries {
+		record, err := json.Marshal(e.Record)
 		if err != nil {
 			continue
 		}
-		var attr detector.Attribution
-		if err := json.Unmarshal(data, &attr); err != nil {
-			continue
+		q := attributions
+		if e.Kind == KindCorrection {
+			q = corrections
 		}
-		attributions = append(attributions, &attr)
-		filePaths = append(filePaths, path)
+		q.records = append(q.records, record)
+		q.entries = append(q.entries, e)
 	}
 
-	if len(attributions) == 0 {
-		return nil
+	u := &uploader{
+		client:   &http.Client{Timeout: 30 * time.Second},
+		endpoint: profile.Endpoint,
+		token:    token,
+		version:  version,
+		store:    store,
 	}
-
-	payload := map[string]any{
-		"attributions": attributions,
+	for _, q := range []*queue{attributions, corrections} {
+		if !u.upload(q) {
+			break
+		}
 	}
-	body, err := json.Marshal(payload)
-	if err != nil {
-		return nil
+	if u.quarantined > 0 {
+		fmt.Fprintf(os.Stderr, "tempo-cli: warning: API rejected %d records, moved to .tempo/failed/ (see 'tempo-cli pending show')\n", u.quarantined)
 	}
+	return nil
+}
 
-	client := &http.Client{Timeout: 10 * time.Second}
-	req, err := http.NewRequest("POST", cfg.Endpoint+"/v1/attributions", bytes.NewReader(body))
-	if err != nil {
-		return nil
+// queue holds the pending records bound for one API path.
+type queue struct {
+	path    string
+	key     string
+	records []json.RawMessage
+	entries []*Entry
+
+	// notFoundRefuses makes a 404 refuse the records rather than point at
+	// a wrong endpoint: the API answers a correction for a commit it never
+	// received with 404. annotate records such commits as attributions,
+	// so this catches corrections queued by older releases.
+	notFoundRefuses bool
+
+	// delivered is set once the API takes a batch from the queue
+	delivered bool
+}
+
+// refused reports whether err may be the API refusing the records of q.
+func (q *queue) refused(err error) bool {
+	if se, ok := err.(*statusError); ok && se.code == http.StatusNotFound && q.notFoundRefuses {
+		return true
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+cfg.APIToken)
-	req.Header.Set("User-Agent", "tempo-cli/"+version)
+	return permanent(err)
+}
 
-	resp, err := client.Do(req)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "tempo-cli: warning: API unreachable, keeping pending files\n")
+// upload sends q in batches, deleting the files of the records the API
+// acknowledges and quarantining those it rejects. It returns false if the
+// API is down, rejects the token or refuses the request for another
+// reason, when later uploads would fail too; the rest stay pending.
+func (u *uploader) upload(q *queue) bool {
+	for _, b := range makeBatches(q.records, q.entries) {
+		if err := u.deliver(q, b); err != nil {
+			fmt.Fprintf(os.Stderr, "tempo-cli: warning: %v, keeping pending files\n", err)
+			return false
+		}
+	}
+	return true
+}
+
+// deliver sends b and settles its records. When the API refuses the whole
+// batch over its content, the batch is split in halves, round by round,
+// until each bad record is sent alone, so one malformed record can't hold
+// back the others. A refusal only counts against a record once the API has
+// taken other records from the queue: if every part of a round is refused
+// too, the request itself is the problem (e.g. a proxy that doesn't accept
+// gzip), so the records stay pending. It returns the error that ends the
+// sync, if any.
+func (u *uploader) deliver(q *queue, b batch) error {
+	acks, err := u.send(q.path, q.key, b)
+	switch {
+	case err == nil:
+		u.settle(b, acks)
+		q.delivered = true
 		return nil
+	case !q.refused(err):
+		return err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-		for _, p := range filePaths {
-			os.Remove(p)
+	refused := err
+	var bad []*Entry
+	for round := []batch{b}; len(round) > 0; {
+		var next []batch
+		for _, part := range round {
+			if len(part.entries) == 1 {
+				bad = append(bad, part.entries[0])
+				continue
+			}
+			left, right := part.split()
+			for _, half := range []batch{left, right} {
+				acks, err := u.send(q.path, q.key, half)
+				switch {
+				case err == nil:
+					u.settle(half, acks)
+					q.delivered = true
+				case !q.refused(err):
+					return err
+				default:
+					refused = err
+					next = append(next, half)
+				}
+			}
+		}
+		if !q.delivered {
+			return refused
 		}
-	} else {
-		fmt.Fprintf(os.Stderr, "tempo-cli: warning: API returned %d, keeping pending files\n", resp.StatusCode)
+		round = next
+	}
+
+	se := refused.(*statusError)
+	for _, e := range bad {
+		u.quarantine(e, se.code, se.Error())
 	}
 	return nil
 }
+
+// settle removes the records in b the API accepted or already had, and
+// quarantines the ones it rejected. A response without per-record results
+// accepts the whole batch. Records missing from the results are kept.
+func (u *uploader) settle(b batch, acks []ack) {
+	if acks == nil {
+		for _, e := range b.entries {
+			u.remove(e)
+		}
+		return
+	}
+	byID := make(map[string]ack, len(acks))
+	for _, a := range acks {
+		byID[a.ID] = a
+	}
+	missing := 0
+	for _, e := range b.entries {
+		a, ok := byID[e.Record.ID]
+		switch {
+		case ok && (a.Status == ackAccepted || a.Status == ackDuplicate):
+			u.remove(e)
+		case ok && a.Status == ackRejected:
+			u.quarantine(e, 0, a.Error)
+		default:
+			missing++
+		}
+	}
+	if missing > 0 {
+		fmt.Fprintf(os.Stderr, "tempo-cli: warning: API did not acknowledge %d records, keeping them\n", missing)
+	}
+}
+
+func (u *uploader) remove(e *Entry) {
+	if err := u.store.remove(e); err != nil {
+		fmt.Fprintf(os.Stderr, "tempo-cli: warning: removing sent record %s: %v\n", e.name(), err)
+	}
+}
+
+func (u *uploader) quarantine(e *Entry, status int, message string) {
+	if err := u.store.quarantine(e, status, message); err != nil {
+		fmt.Fprintf(os.Stderr, "tempo-cli: warning: quarantining %s: %v, keeping it pending\n", e.name(), err)
+		return
+	}
+	u.quarantined++
+}
@@ -0,0 +1,521 @@
+package sender
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/usetempo/tempo-cli/internal/detector"
+)
+
+// setupSync points the config at srv with a token and returns a repo
+// root holding n pending attributions.
+func setupSync(t *testing.T, srv *httptest.Server, n int) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("TEMPO_SYSTEM_CONFIG", filepath.Join(home, "system.json"))
+	t.Setenv("TEMPO_API_TOKEN", "tpo_test")
+	t.Setenv("TEMPO_API_ENDPOINT", srv.URL)
+
+	sleep = func(time.Duration) {}
+	t.Cleanup(func() { sleep = time.Sleep })
+
+	repoRoot := t.TempDir()
+	store := OpenStore(repoRoot)
+	for i := 0; i < n; i++ {
+		attr := &detector.Attribution{CommitSHA: fmt.Sprintf("%040d", i)}
+		if err := store.Put(KindAttribution, attr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return repoRoot
+}
+
+// recorder is an API stand-in that decodes each batch it receives and
+// answers with the next status in statuses, then 200.
+type recorder struct {
+	mu       sync.Mutex
+	batches  [][]detector.Attribution
+	statuses []int
+	header   http.Header
+	keys     []string
+
+	// acks, if set, answers each record of a batch
+	acks func(detector.Attribution) ack
+
+	// reject, if set, fails a batch holding a matching record with 422
+	reject func(detector.Attribution) bool
+}
+
+func (r *recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys = append(r.keys, req.Header.Get("Idempotency-Key"))
+	if len(r.statuses) > 0 {
+		status := r.statuses[0]
+		r.statuses = r.statuses[1:]
+		if status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "2")
+		}
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+	}
+	r.header = req.Header
+	zr, err := gzip.NewReader(req.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var body struct {
+		Attributions []detector.Attribution `json:"attributions"`
+	}
+	if err := json.NewDecoder(zr).Decode(&body); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if r.reject != nil {
+		for _, attr := range body.Attributions {
+			if r.reject(attr) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				w.Write([]byte(`{"error": "detections: invalid tool"}`))
+				return
+			}
+		}
+	}
+	r.batches = append(r.batches, body.Attributions)
+	if r.acks == nil {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	var result struct {
+		Results []ack `json:"results"`
+	}
+	for _, attr := range body.Attributions {
+		result.Results = append(result.Results, r.acks(attr))
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+func TestSync_Batches(t *testing.T) {
+	rec := &recorder{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+	repoRoot := setupSync(t, srv, 2*maxBatchRecords+5)
+
+	if err := Sync(repoRoot, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.batches) != 3 || len(rec.batches[0]) != maxBatchRecords || len(rec.batches[2]) != 5 {
+		t.Fatalf("got %d batches", len(rec.batches))
+	}
+	if rec.header.Get("Content-Encoding") != "gzip" || rec.header.Get("Authorization") != "Bearer tpo_test" {
+		t.Errorf("headers: got %v", rec.header)
+	}
+	if n := OpenStore(repoRoot).Count(); n != 0 {
+		t.Errorf("pending after sync: got %d, want 0", n)
+	}
+}
+
+func TestSync_RetriesTransientErrors(t *testing.T) {
+	rec := &recorder{statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+	repoRoot := setupSync(t, srv, 3)
+
+	var waits []time.Duration
+	sleep = func(d time.Duration) { waits = append(waits, d) }
+
+	if err := Sync(repoRoot, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.batches) != 1 || OpenStore(repoRoot).Count() != 0 {
+		t.Errorf("got %d batches, %d pending; want the batch sent on the third attempt", len(rec.batches), OpenStore(repoRoot).Count())
+	}
+	if len(waits) != 2 {
+		t.Fatalf("waits: got %v", waits)
+	}
+	if waits[0] < baseBackoff/2 || waits[0] > baseBackoff {
+		t.Errorf("backoff: got %v", waits[0])
+	}
+	if waits[1] != 2*time.Second {
+		t.Errorf("Retry-After: got %v, want 2s", waits[1])
+	}
+	if len(rec.keys) != 3 || rec.keys[0] == "" || rec.keys[0] != rec.keys[1] || rec.keys[1] != rec.keys[2] {
+		t.Errorf("idempotency keys: got %q, want the same key on every attempt", rec.keys)
+	}
+}
+
+func TestSync_Acks(t *testing.T) {
+	rec := &recorder{acks: func(attr detector.Attribution) ack {
+		switch attr.CommitSHA {
+		case fmt.Sprintf("%040d", 1):
+			return ack{ID: attr.ID, Status: ackDuplicate}
+		case fmt.Sprintf("%040d", 2):
+			return ack{ID: attr.ID, Status: ackRejected, Error: "unknown repo"}
+		case fmt.Sprintf("%040d", 3):
+			return ack{ID: "someone-else", Status: ackAccepted}
+		}
+		return ack{ID: attr.ID, Status: ackAccepted}
+	}}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+	repoRoot := setupSync(t, srv, 5)
+
+	if err := Sync(repoRoot, "test"); err != nil {
+		t.Fatal(err)
+	}
+	var kept []string
+	entries, err := OpenStore(repoRoot).List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		kept = append(kept, e.Record.CommitSHA[len(e.Record.CommitSHA)-1:])
+	}
+	if strings.Join(kept, ",") != "3" {
+		t.Errorf("kept: got %v, want the unacknowledged record", kept)
+	}
+	failed, err := OpenStore(repoRoot).Failed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 1 || failed[0].Record.CommitSHA != fmt.Sprintf("%040d", 2) || failed[0].Error != "unknown repo" {
+		t.Errorf("failed: got %+v, want the rejected record", failed)
+	}
+}
+
+func TestSync_QuarantinesRejectedRecords(t *testing.T) {
+	bad := map[string]bool{fmt.Sprintf("%040d", 5): true, fmt.Sprintf("%040d", 6): true}
+	rec := &recorder{reject: func(attr detector.Attribution) bool { return bad[attr.CommitSHA] }}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+	repoRoot := setupSync(t, srv, 8)
+
+	if err := Sync(repoRoot, "test"); err != nil {
+		t.Fatal(err)
+	}
+	sent := 0
+	for _, b := range rec.batches {
+		sent += len(b)
+	}
+	if sent != 6 || OpenStore(repoRoot).Count() != 0 {
+		t.Errorf("got %d sent, %d pending; want every good record sent", sent, OpenStore(repoRoot).Count())
+	}
+	failed, err := OpenStore(repoRoot).Failed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 2 || OpenStore(repoRoot).FailedCount() != 2 {
+		t.Fatalf("failed: got %d records, want 2", len(failed))
+	}
+	for _, f := range failed {
+		if !bad[f.Record.CommitSHA] || f.Status != http.StatusUnprocessableEntity || f.Error != "API returned 422: detections: invalid tool" {
+			t.Errorf("failed: got %+v", f)
+		}
+	}
+
+	// The next sync doesn't send them again
+	rec.batches = nil
+	if err := Sync(repoRoot, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.batches) != 0 {
+		t.Errorf("got %d batches, want none", len(rec.batches))
+	}
+
+	// Triage: one goes back to the queue, the other is dropped
+	if err := OpenStore(repoRoot).Retry(failed[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := OpenStore(repoRoot).Drop(failed[1]); err != nil {
+		t.Fatal(err)
+	}
+	if OpenStore(repoRoot).FailedCount() != 0 || OpenStore(repoRoot).Count() != 1 {
+		t.Errorf("got %d failed, %d pending; want 0 and 1", OpenStore(repoRoot).FailedCount(), OpenStore(repoRoot).Count())
+	}
+	if attr, err := OpenStore(repoRoot).Get(KindAttribution, failed[0].Record.CommitSHA); err != nil || attr == nil {
+		t.Errorf("retried record not pending: %v", err)
+	}
+}
+
+func TestSync_BlanketRefusalKeepsRecords(t *testing.T) {
+	// A proxy refusing every request, e.g. over its Content-Encoding
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+	repoRoot := setupSync(t, srv, 8)
+
+	if err := Sync(repoRoot, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if OpenStore(repoRoot).Count() != 8 || OpenStore(repoRoot).FailedCount() != 0 {
+		t.Errorf("got %d pending, %d failed; a blanket refusal must not quarantine records", OpenStore(repoRoot).Count(), OpenStore(repoRoot).FailedCount())
+	}
+	if requests != 3 {
+		t.Errorf("got %d requests, want the batch and its two halves", requests)
+	}
+}
+
+func TestSync_NotFoundKeepsRecords(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+	repoRoot := setupSync(t, srv, 3)
+
+	if err := Sync(repoRoot, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if OpenStore(repoRoot).Count() != 3 || OpenStore(repoRoot).FailedCount() != 0 {
+		t.Errorf("got %d pending, %d failed; a wrong endpoint must not quarantine records", OpenStore(repoRoot).Count(), OpenStore(repoRoot).FailedCount())
+	}
+}
+
+func TestSync_RecordedTwice(t *testing.T) {
+	rec := &recorder{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+	repoRoot := setupSync(t, srv, 1)
+
+	// The same commit detected again later, e.g. by a re-run of the hook
+	attr := &detector.Attribution{CommitSHA: fmt.Sprintf("%040d", 0), Timestamp: "2026-01-01T00:00:00Z"}
+	if err := OpenStore(repoRoot).Put(KindAttribution, attr); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Sync(repoRoot, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.batches) != 1 || len(rec.batches[0]) != 1 {
+		t.Fatalf("got %v, want the record sent once", rec.batches)
+	}
+	if rec.batches[0][0].ID != recordID(attr) {
+		t.Errorf("id: got %q, want %q", rec.batches[0][0].ID, recordID(attr))
+	}
+	if n := OpenStore(repoRoot).Count(); n != 0 {
+		t.Errorf("pending after sync: got %d, want 0", n)
+	}
+}
+
+func TestRecordID(t *testing.T) {
+	sha := "0123456789abcdef0123456789abcdef01234567"
+	a := &detector.Attribution{CommitSHA: sha, Timestamp: "2026-01-01T00:00:00Z", Detections: []detector.Detection{{Tool: detector.ToolClaudeCode}}}
+	b := *a
+	b.Timestamp = "2026-02-01T00:00:00Z"
+	b.ID = "stale"
+	if recordID(a) != recordID(&b) {
+		t.Error("ID should not depend on the timestamp or a stored ID")
+	}
+	if !strings.HasPrefix(recordID(a), sha+"-") {
+		t.Errorf("ID should start with the commit SHA, got %q", recordID(a))
+	}
+	b.Detections = nil
+	if recordID(a) == recordID(&b) {
+		t.Error("ID should change with the content")
+	}
+}
+
+func TestSync_KeepsProgress(t *testing.T) {
+	// The first batch goes through, then the API fails for good
+	statuses := []int{http.StatusOK}
+	for i := 0; i < maxAttempts; i++ {
+		statuses = append(statuses, http.StatusBadGateway)
+	}
+	rec := &recorder{statuses: statuses}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+	repoRoot := setupSync(t, srv, maxBatchRecords+10)
+
+	if err := Sync(repoRoot, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.batches) != 1 {
+		t.Fatalf("got %d batches, want 1", len(rec.batches))
+	}
+	if n := OpenStore(repoRoot).Count(); n != 10 {
+		t.Errorf("pending after sync: got %d, want the 10 unsent", n)
+	}
+	if len(rec.statuses) != 0 {
+		t.Errorf("got %d attempts left, want all %d used", len(rec.statuses), maxAttempts)
+	}
+}
+
+func TestMakeBatches_Bytes(t *testing.T) {
+	big := json.RawMessage(`"` + strings.Repeat("x", maxBatchBytes/2) + `"`)
+	records := []json.RawMessage{big, big, big, json.RawMessage(`{}`)}
+	var entries []*Entry
+	for _, sha := range []string{"a", "b", "c", "d"} {
+		entries = append(entries, &Entry{Record: &detector.Attribution{CommitSHA: sha}})
+	}
+	batches := makeBatches(records, entries)
+	if len(batches) != 3 {
+		t.Fatalf("got %d batches, want 3", len(batches))
+	}
+	if len(batches[2].entries) != 2 || batches[2].entries[1].Record.CommitSHA != "d" {
+		t.Errorf("last batch: got %v", batches[2].entries)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if got := parseRetryAfter("120"); got != 2*time.Minute {
+		t.Errorf("seconds: got %v", got)
+	}
+	date := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if got := parseRetryAfter(date); got < 59*time.Minute || got > time.Hour {
+		t.Errorf("date: got %v", got)
+	}
+	for _, v := range []string{"", "-1", "soon"} {
+		if got := parseRetryAfter(v); got != 0 {
+			t.Errorf("%q: got %v, want 0", v, got)
+		}
+	}
+}
+
+func TestSync_SkipsWhileLocked(t *testing.T) {
+	rec := &recorder{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+	repoRoot := setupSync(t, srv, 2)
+
+	lock, err := lockFile(syncLockPath(repoRoot), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lockFile(syncLockPath(repoRoot), false); err != errLocked {
+		t.Errorf("second lock: got %v, want errLocked", err)
+	}
+	if err := Sync(repoRoot, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.batches) != 0 || OpenStore(repoRoot).Count() != 2 {
+		t.Errorf("got %d batches, %d pending; want nothing sent while another sync runs", len(rec.batches), OpenStore(repoRoot).Count())
+	}
+
+	lock.Close()
+	if err := Sync(repoRoot, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if OpenStore(repoRoot).Count() != 0 {
+		t.Errorf("pending after the lock is released: got %d, want 0", OpenStore(repoRoot).Count())
+	}
+}
+
+func TestSync_RepoFileCannotRedirectToken(t *testing.T) {
+	user := &recorder{}
+	userSrv := httptest.NewServer(user)
+	defer userSrv.Close()
+	repo := &recorder{}
+	repoSrv := httptest.NewServer(repo)
+	defer repoSrv.Close()
+	repoRoot := setupSync(t, userSrv, 1)
+
+	// The token and endpoint come from the user config; a cloned repo
+	// tries to point them elsewhere
+	t.Setenv("TEMPO_API_TOKEN", "")
+	t.Setenv("TEMPO_API_ENDPOINT", "")
+	userConfig := fmt.Sprintf(`{"api_token": "tpo_user", "endpoint": %q}`, userSrv.URL)
+	repoConfig := fmt.Sprintf(`{"endpoint": %q, "profile": "default", "profile_rules": [{"match": "*", "profile": "default"}]}`, repoSrv.URL)
+	for path, content := range map[string]string{
+		filepath.Join(os.Getenv("HOME"), ".tempo", "config.json"): userConfig,
+		filepath.Join(repoRoot, ".tempo.json"):                    repoConfig,
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := Sync(repoRoot, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.keys) != 0 {
+		t.Errorf("the repo's endpoint got %d requests, want none", len(repo.keys))
+	}
+	if len(user.batches) != 1 || user.header.Get("Authorization") != "Bearer tpo_user" {
+		t.Errorf("got %d batches at the user's endpoint, want the record sent there", len(user.batches))
+	}
+}
+
+func TestSync_UnreadableConfigKeepsRecords(t *testing.T) {
+	rec := &recorder{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+	repoRoot := setupSync(t, srv, 2)
+	if err := os.WriteFile(filepath.Join(repoRoot, ".tempo.json"), []byte(`{"privacy": "strict",}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Sync(repoRoot, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.keys) != 0 || OpenStore(repoRoot).Count() != 2 {
+		t.Errorf("got %d requests, %d pending; want nothing sent with defaults", len(rec.keys), OpenStore(repoRoot).Count())
+	}
+}
+
+func TestSync_QuarantinesCorrectionsForUnknownCommits(t *testing.T) {
+	unknown := fmt.Sprintf("%040d", 7)
+	var sent []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var body map[string][]detector.Attribution
+		if err := json.NewDecoder(zr).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for _, attr := range body["corrections"] {
+			if attr.CommitSHA == unknown {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"error": "no attribution for commit"}`))
+				return
+			}
+		}
+		for _, attrs := range body {
+			for _, attr := range attrs {
+				sent = append(sent, attr.CommitSHA)
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+	repoRoot := setupSync(t, srv, 1)
+	store := OpenStore(repoRoot)
+	for _, sha := range []string{unknown, fmt.Sprintf("%040d", 8)} {
+		if err := store.Put(KindCorrection, &detector.Attribution{CommitSHA: sha}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := Sync(repoRoot, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 2 || store.Count() != 0 {
+		t.Errorf("got %v sent, %d pending; want the attribution and the good correction sent", sent, store.Count())
+	}
+	failed, err := store.Failed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 1 || failed[0].Kind != KindCorrection || failed[0].Record.CommitSHA != unknown || failed[0].Status != http.StatusNotFound {
+		t.Errorf("failed: got %+v, want the correction for the unknown commit", failed)
+	}
+}
@@ -0,0 +1,363 @@
+package sender
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/usetempo/tempo-cli/internal/detector"
+)
+
+// Kind is the kind of a queued record, which decides the API path it is
+// sent to.
+type Kind string
+
+const (
+	KindAttribution Kind = "attribution"
+	KindCorrection  Kind = "correction"
+)
+
+// storeVersion is the version of the on-disk record format. Records of a
+// later version, written by a newer tempo-cli, are left alone.
+const storeVersion = 1
+
+// errNewerVersion is returned when reading a record of a later version.
+var errNewerVersion = errors.New("written by a newer tempo-cli")
+
+// Entry is a record in the pending store, as stored on disk.
+type Entry struct {
+	Version int  `json:"version"`
+	Kind    Kind `json:"kind"`
+
+	// QueuedAt is when the commit was first queued. Replacing the record
+	// keeps it, so the commit keeps its place in the queue.
+	QueuedAt time.Time             `json:"queued_at"`
+	Record   *detector.Attribution `json:"record"`
+}
+
+func entryName(kind Kind, commitSHA string) string {
+	return string(kind) + "-" + commitSHA + ".json"
+}
+
+func (e *Entry) name() string {
+	return entryName(e.Kind, e.Record.CommitSHA)
+}
+
+// Store is the queue of records waiting to be sent, in .tempo/pending/,
+// holding at most one record of each kind per commit. Reads and writes
+// take an advisory lock on .tempo/pending.lock, so the hooks, annotate
+// and a background sync can use it at the same time.
+type Store struct {
+	repoRoot string
+}
+
+// OpenStore returns the pending store of the repo.
+func OpenStore(repoRoot string) *Store {
+	return &Store{repoRoot: repoRoot}
+}
+
+func pendingDir(repoRoot string) string {
+	return filepath.Join(repoRoot, ".tempo", "pending")
+}
+
+func (s *Store) dir() string {
+	return pendingDir(s.repoRoot)
+}
+
+// withLock runs fn holding the store lock. With create false, a repo
+// without a store is left as is and fn isn't run.
+func (s *Store) withLock(create bool, fn func() error) error {
+	if _, err := os.Stat(s.dir()); err != nil {
+		if !create || !os.IsNotExist(err) {
+			return nil
+		}
+		if err := os.MkdirAll(s.dir(), 0755); err != nil {
+			return err
+		}
+	}
+	lock, err := lockFile(filepath.Join(s.repoRoot, ".tempo", "pending.lock"), true)
+	if err != nil {
+		return err
+	}
+	defer lock.Close()
+	if err := s.migrate(); err != nil {
+		return err
+	}
+	return fn()
+}
+
+// Put queues attr for its commit, replacing any record of the same kind
+// already queued for it.
+func (s *Store) Put(kind Kind, attr *detector.Attribution) error {
+	if attr.CommitSHA == "" {
+		return fmt.Errorf("record has no commit SHA")
+	}
+	_, err := s.Update(kind, attr.CommitSHA, func(*detector.Attribution) *detector.Attribution {
+		return attr
+	})
+	return err
+}
+
+// Update replaces the record of kind queued for a commit with the result
+// of fn, in one step under the store lock. fn gets the queued record, or
+// nil if there is none, and returns the record to store, or nil to leave
+// the store as it is. Update returns the stored record.
+func (s *Store) Update(kind Kind, commitSHA string, fn func(*detector.Attribution) *detector.Attribution) (*detector.Attribution, error) {
+	var stored *detector.Attribution
+	err := s.withLock(true, func() error {
+		e := &Entry{Kind: kind, QueuedAt: time.Now().UTC()}
+		var cur *detector.Attribution
+		old, err := s.read(entryName(kind, commitSHA))
+		switch {
+		case err == nil:
+			e.QueuedAt = old.QueuedAt
+			cur = old.Record
+		case !os.IsNotExist(err):
+			return err
+		}
+		if e.Record = fn(cur); e.Record == nil {
+			return nil
+		}
+		if e.Record.CommitSHA != commitSHA {
+			return fmt.Errorf("record is for commit %s, not %s", e.Record.CommitSHA, commitSHA)
+		}
+		stored = e.Record
+		return s.write(e)
+	})
+	return stored, err
+}
+
+// Get returns the record of kind queued for a commit, or nil if there is
+// none.
+func (s *Store) Get(kind Kind, commitSHA string) (*detector.Attribution, error) {
+	var attr *detector.Attribution
+	err := s.withLock(false, func() error {
+		e, err := s.read(entryName(kind, commitSHA))
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		attr = e.Record
+		return nil
+	})
+	return attr, err
+}
+
+// List returns the queued records, oldest first. Records it can't read
+// are skipped with a warning and left in place.
+func (s *Store) List() ([]*Entry, error) {
+	var entries []*Entry
+	err := s.withLock(false, func() error {
+		names, err := s.names()
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			e, err := s.read(name)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "tempo-cli: warning: pending record %s: %v, leaving it\n", name, err)
+				continue
+			}
+			entries = append(entries, e)
+		}
+		return nil
+	})
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].QueuedAt.Before(entries[j].QueuedAt)
+	})
+	return entries, err
+}
+
+// Count returns the number of queued records.
+func (s *Store) Count() int {
+	names, _ := s.names()
+	return len(names)
+}
+
+// remove deletes e's record after it was sent, unless it was replaced
+// since; the newer record stays queued. A sent attribution's commit is
+// recorded as synced either way.
+func (s *Store) remove(e *Entry) error {
+	return s.withLock(false, func() error {
+		if e.Kind == KindAttribution {
+			if err := s.markSynced(e.Record.CommitSHA); err != nil {
+				return err
+			}
+		}
+		if !s.unchanged(e) {
+			return nil
+		}
+		return os.Remove(filepath.Join(s.dir(), e.name()))
+	})
+}
+
+func syncedPath(repoRoot string) string {
+	return filepath.Join(repoRoot, ".tempo", "synced")
+}
+
+// markSynced appends a commit to .tempo/synced, the list of commits the
+// API has an attribution for.
+func (s *Store) markSynced(commitSHA string) error {
+	f, err := os.OpenFile(syncedPath(s.repoRoot), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(commitSHA + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Synced reports whether an attribution for a commit has been sent to the
+// API, so changes to it go as a correction rather than a new record.
+func (s *Store) Synced(commitSHA string) (bool, error) {
+	var synced bool
+	err := s.withLock(false, func() error {
+		data, err := os.ReadFile(syncedPath(s.repoRoot))
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		synced = slices.Contains(strings.Split(string(data), "\n"), commitSHA)
+		return nil
+	})
+	return synced, err
+}
+
+// unchanged reports whether the stored record is still the one in e.
+func (s *Store) unchanged(e *Entry) bool {
+	cur, err := s.read(e.name())
+	return err == nil && cur.Record.ID == e.Record.ID
+}
+
+// names lists the record files, in no particular order.
+func (s *Store) names() ([]string, error) {
+	dirEntries, err := os.ReadDir(s.dir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var names []string
+	for _, de := range dirEntries {
+		if isRecordFile(de) {
+			names = append(names, de.Name())
+		}
+	}
+	return names, nil
+}
+
+func (s *Store) read(name string) (*Entry, error) {
+	return readEntry(filepath.Join(s.dir(), name))
+}
+
+func readEntry(path string) (*Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var e Entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	if e.Version > storeVersion {
+		return nil, fmt.Errorf("format version %d: %w", e.Version, errNewerVersion)
+	}
+	if e.Record == nil || e.Record.CommitSHA == "" {
+		return nil, fmt.Errorf("not a pending record")
+	}
+	return &e, nil
+}
+
+// write atomically stores e, setting its record ID.
+func (s *Store) write(e *Entry) error {
+	e.Version = storeVersion
+	e.Record.ID = recordID(e.Record)
+	return writeJSONFile(filepath.Join(s.dir(), e.name()), e)
+}
+
+// writeJSONFile atomically writes v to path.
+func writeJSONFile(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmpPath := filepath.Join(filepath.Dir(path), ".tmp-"+filepath.Base(path))
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// migrate converts records written before the store was versioned: bare
+// attributions named after the time they were saved, with corrections
+// prefixed "correction-". A record already queued in the current format
+// for the same commit is newer and wins.
+func (s *Store) migrate() error {
+	dirEntries, err := os.ReadDir(s.dir())
+	if err != nil {
+		return err
+	}
+	for _, de := range dirEntries {
+		if !isRecordFile(de) || !isLegacyName(de.Name()) {
+			continue
+		}
+		path := filepath.Join(s.dir(), de.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var attr detector.Attribution
+		if err := json.Unmarshal(data, &attr); err != nil || attr.CommitSHA == "" {
+			fmt.Fprintf(os.Stderr, "tempo-cli: warning: pending record %s is unreadable, leaving it\n", de.Name())
+			continue
+		}
+		e := &Entry{Kind: KindAttribution, Record: &attr}
+		if strings.HasPrefix(de.Name(), string(KindCorrection)+"-") {
+			e.Kind = KindCorrection
+		}
+		if info, err := de.Info(); err == nil {
+			e.QueuedAt = info.ModTime().UTC()
+		}
+		if cur, err := s.read(e.name()); err != nil || cur.QueuedAt.Before(e.QueuedAt) {
+			if err := s.write(e); err != nil {
+				return err
+			}
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isLegacyName reports whether a record file name is in the unversioned
+// format, a millisecond timestamp instead of a commit SHA.
+func isLegacyName(name string) bool {
+	stem := strings.TrimPrefix(strings.TrimSuffix(name, ".json"), string(KindCorrection)+"-")
+	if stem == "" || len(stem) > 19 {
+		return false
+	}
+	for _, r := range stem {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isRecordFile(entry os.DirEntry) bool {
+	return !entry.IsDir() && strings.HasSuffix(entry.Name(), ".json") && !strings.HasPrefix(entry.Name(), ".tmp-")
+}
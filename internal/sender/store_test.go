@@ -0,0 +1,188 @@
+package sender
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/usetempo/tempo-cli/internal/detector"
+)
+
+func TestStore_Upsert(t *testing.T) {
+	store := OpenStore(t.TempDir())
+	sha := "0123456789abcdef0123456789abcdef01234567"
+
+	if err := store.Put(KindAttribution, &detector.Attribution{CommitSHA: sha, CommitAuthor: "first"}); err != nil {
+		t.Fatal(err)
+	}
+	first, _ := store.List()
+	time.Sleep(10 * time.Millisecond)
+	if err := store.Put(KindAttribution, &detector.Attribution{CommitSHA: sha, CommitAuthor: "second"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(KindCorrection, &detector.Attribution{CommitSHA: sha}); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := store.Count(); n != 2 {
+		t.Errorf("count: got %d, want one record per kind", n)
+	}
+	attr, err := store.Get(KindAttribution, sha)
+	if err != nil || attr == nil || attr.CommitAuthor != "second" {
+		t.Fatalf("got %+v, %v; want the replacement", attr, err)
+	}
+	entries, _ := store.List()
+	if len(entries) != 2 || entries[0].Kind != KindAttribution || !entries[0].QueuedAt.Equal(first[0].QueuedAt) {
+		t.Errorf("the replaced record should keep its place in the queue: got %+v", entries[0])
+	}
+	if entries[0].Version != storeVersion || entries[0].Record.ID != recordID(attr) {
+		t.Errorf("got version %d, id %q", entries[0].Version, entries[0].Record.ID)
+	}
+
+	// Update edits the queued record, or leaves the store alone
+	got, err := store.Update(KindAttribution, sha, func(a *detector.Attribution) *detector.Attribution {
+		a.CommitAuthor = "third"
+		return a
+	})
+	if err != nil || got == nil || got.CommitAuthor != "third" {
+		t.Errorf("update: got %+v, %v", got, err)
+	}
+	got, err = store.Update(KindAttribution, "feedface", func(a *detector.Attribution) *detector.Attribution {
+		if a != nil {
+			t.Error("expected no record")
+		}
+		return nil
+	})
+	if err != nil || got != nil || store.Count() != 2 {
+		t.Errorf("update of a missing record: got %+v, %v, %d records", got, err, store.Count())
+	}
+
+	if err := store.Put(KindAttribution, &detector.Attribution{}); err == nil {
+		t.Error("a record without a commit SHA should be refused")
+	}
+}
+
+func TestStore_MigratesLegacyRecords(t *testing.T) {
+	repoRoot := t.TempDir()
+	dir := pendingDir(repoRoot)
+	legacy := map[string]string{
+		"1760000000000.json":            `{"commit_sha": "aaaa", "commit_author": "old"}`,
+		"1760000000500.json":            `{"commit_sha": "aaaa", "commit_author": "rerun"}`,
+		"1760000001000.json":            `{"commit_sha": "bbbb"}`,
+		"correction-1760000002000.json": `{"commit_sha": "aaaa", "corrections": [{"not_ai": true}]}`,
+		"attribution-cccc.json":         `{"version": 2, "kind": "attribution", "record": {"commit_sha": "cccc"}}`,
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for i, name := range []string{"1760000000000.json", "1760000000500.json", "1760000001000.json", "correction-1760000002000.json", "attribution-cccc.json"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(legacy[name]), 0644); err != nil {
+			t.Fatal(err)
+		}
+		mtime := time.Now().Add(time.Duration(i-10) * time.Minute)
+		os.Chtimes(path, mtime, mtime)
+	}
+
+	store := OpenStore(repoRoot)
+	entries, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, fmt.Sprintf("%s %s %s", e.Kind, e.Record.CommitSHA, e.Record.CommitAuthor))
+	}
+	want := []string{"attribution aaaa rerun", "attribution bbbb ", "correction aaaa "}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// The record from a newer tempo-cli is left alone
+	if _, err := os.Stat(filepath.Join(dir, "attribution-cccc.json")); err != nil {
+		t.Errorf("newer record: %v", err)
+	}
+	for name := range legacy {
+		if isLegacyName(name) {
+			if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+				t.Errorf("%s should have been migrated", name)
+			}
+		}
+	}
+}
+
+func TestStore_RemoveKeepsReplacedRecord(t *testing.T) {
+	store := OpenStore(t.TempDir())
+	if err := store.Put(KindAttribution, &detector.Attribution{CommitSHA: "aaaa"}); err != nil {
+		t.Fatal(err)
+	}
+	sent, _ := store.List()
+
+	// Annotated while the sync was sending it
+	if err := store.Put(KindAttribution, &detector.Attribution{CommitSHA: "aaaa", CommitAuthor: "fixed"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.remove(sent[0]); err != nil {
+		t.Fatal(err)
+	}
+	if attr, _ := store.Get(KindAttribution, "aaaa"); attr == nil || attr.CommitAuthor != "fixed" {
+		t.Errorf("got %+v, want the newer record kept", attr)
+	}
+}
+
+func TestStore_Synced(t *testing.T) {
+	store := OpenStore(t.TempDir())
+	for _, kind := range []Kind{KindAttribution, KindCorrection} {
+		if err := store.Put(kind, &detector.Attribution{CommitSHA: string(kind) + "-sha"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, _ := store.List()
+	for _, e := range entries {
+		if err := store.remove(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		sha  string
+		want bool
+	}{
+		{"attribution-sha", true},
+		{"correction-sha", false},
+		{"attribution", false},
+		{"other", false},
+	}
+	for _, tt := range tests {
+		if got, err := store.Synced(tt.sha); err != nil || got != tt.want {
+			t.Errorf("Synced(%q): got %v, %v; want %v", tt.sha, got, err, tt.want)
+		}
+	}
+}
+
+func TestStore_ConcurrentWriters(t *testing.T) {
+	store := OpenStore(t.TempDir())
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Two writers per commit, as with overlapping hook runs
+			attr := &detector.Attribution{CommitSHA: fmt.Sprintf("%040d", i/2)}
+			if err := OpenStore(store.repoRoot).Put(KindAttribution, attr); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	entries, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 10 {
+		t.Errorf("got %d records, want 10", len(entries))
+	}
+}
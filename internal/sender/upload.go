@@ -0,0 +1,274 @@
+package sender
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Batch limits. A batch holds at most maxBatchRecords records and at most
+// maxBatchBytes of uncompressed JSON, unless a single record is larger.
+const (
+	maxBatchRecords = 100
+	maxBatchBytes   = 1 << 20
+)
+
+// Retry policy for transient failures: network errors, 408, 429 and 5xx.
+// The waits stay short, since a foreground sync blocks its caller, and a
+// Retry-After longer than maxRetryAfter ends the sync instead; the
+// records go out on the next push.
+const (
+	maxAttempts   = 4
+	baseBackoff   = 500 * time.Millisecond
+	maxBackoff    = 8 * time.Second
+	maxRetryAfter = 30 * time.Second
+)
+
+// sleep is replaced in tests.
+var sleep = time.Sleep
+
+// statusError is a non-2xx API response.
+type statusError struct {
+	code       int
+	message    string
+	retryAfter time.Duration
+}
+
+func (e *statusError) Error() string {
+	if e.message != "" {
+		return fmt.Sprintf("API returned %d: %s", e.code, e.message)
+	}
+	return fmt.Sprintf("API returned %d", e.code)
+}
+
+// transient reports whether the request may succeed if retried.
+func transient(err error) bool {
+	var se *statusError
+	if !errors.As(err, &se) {
+		// Network error
+		return true
+	}
+	return se.code == http.StatusRequestTimeout || se.code == http.StatusTooManyRequests || se.code >= 500
+}
+
+// permanent reports whether the API may have refused the records
+// themselves, so sending them again can't succeed: a malformed (400), too
+// large (413) or schema-rejected (422) request. deliver confirms it before
+// quarantining anything. Other client errors, like 401 or 404, point at
+// the token or the endpoint and leave the records pending.
+func permanent(err error) bool {
+	se, ok := err.(*statusError)
+	return ok && (se.code == http.StatusBadRequest || se.code == http.StatusRequestEntityTooLarge || se.code == http.StatusUnprocessableEntity)
+}
+
+// uploader posts batches of records to one API endpoint.
+type uploader struct {
+	client   *http.Client
+	endpoint string
+	token    string
+	version  string
+
+	// store holds the records being sent. Rejected ones are quarantined
+	// there, counted in quarantined.
+	store       *Store
+	quarantined int
+}
+
+// batch is a run of pending records sent in one request.
+type batch struct {
+	records []json.RawMessage
+	entries []*Entry
+}
+
+// split halves b.
+func (b batch) split() (batch, batch) {
+	n := len(b.entries) / 2
+	return batch{records: b.records[:n], entries: b.entries[:n]},
+		batch{records: b.records[n:], entries: b.entries[n:]}
+}
+
+// idempotencyKey identifies the batch by the IDs of its records, so a
+// resent batch carries the same key.
+func (b batch) idempotencyKey() string {
+	ids := make([]string, len(b.entries))
+	for i, e := range b.entries {
+		ids[i] = e.Record.ID
+	}
+	sum := sha256.Sum256([]byte(strings.Join(ids, "\n")))
+	return hex.EncodeToString(sum[:16])
+}
+
+// Per-record statuses in an API response.
+const (
+	ackAccepted  = "accepted"
+	ackDuplicate = "duplicate"
+	ackRejected  = "rejected"
+)
+
+// ack is the API's answer for one record of a batch.
+type ack struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// makeBatches splits records, with the store entries they were encoded
+// from, into batches within the batch limits, keeping their order.
+func makeBatches(records []json.RawMessage, entries []*Entry) []batch {
+	var batches []batch
+	var cur batch
+	size := 0
+	for i, rec := range records {
+		if len(cur.records) > 0 && (len(cur.records) == maxBatchRecords || size+len(rec) > maxBatchBytes) {
+			batches = append(batches, cur)
+			cur, size = batch{}, 0
+		}
+		cur.records = append(cur.records, rec)
+		cur.entries = append(cur.entries, entries[i])
+		size += len(rec) + 1
+	}
+	if len(cur.records) > 0 {
+		batches = append(batches, cur)
+	}
+	return batches
+}
+
+// send posts b to path under key and returns the API's per-record
+// results, nil if it gave none. Transient failures are retried with
+// jittered exponential backoff or the wait the API asks for, under the
+// same idempotency key so the API can tell a retry from a new batch.
+func (u *uploader) send(path, key string, b batch) ([]ack, error) {
+	body, err := gzipJSON(map[string][]json.RawMessage{key: b.records})
+	if err != nil {
+		return nil, err
+	}
+	idemKey := b.idempotencyKey()
+	for attempt := 1; ; attempt++ {
+		acks, err := u.post(path, idemKey, body)
+		if err == nil || !transient(err) || attempt == maxAttempts {
+			return acks, err
+		}
+		wait := backoff(attempt)
+		var se *statusError
+		if errors.As(err, &se) && se.retryAfter > 0 {
+			if se.retryAfter > maxRetryAfter {
+				return nil, fmt.Errorf("%w, retry after %s", err, se.retryAfter)
+			}
+			wait = se.retryAfter
+		}
+		sleep(wait)
+	}
+}
+
+// post makes one attempt at sending a gzipped JSON body and decodes the
+// per-record results of a 2xx response.
+func (u *uploader) post(path, idemKey string, body []byte) ([]ack, error) {
+	req, err := http.NewRequest("POST", u.endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("Authorization", "Bearer "+u.token)
+	req.Header.Set("Idempotency-Key", idemKey)
+	req.Header.Set("User-Agent", "tempo-cli/"+u.version)
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("API unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &statusError{
+			code:       resp.StatusCode,
+			message:    errorMessage(resp.Body),
+			retryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		}
+	}
+	var result struct {
+		Results []ack `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		// No per-record results: the status covers the whole batch
+		return nil, nil
+	}
+	return result.Results, nil
+}
+
+// errorMessage reads the reason from an error response: the "error" or
+// "message" field of a JSON body, else the body text.
+func errorMessage(body io.Reader) string {
+	data, _ := io.ReadAll(io.LimitReader(body, 4096))
+	var v struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(data, &v) == nil {
+		if v.Error != "" {
+			return v.Error
+		}
+		if v.Message != "" {
+			return v.Message
+		}
+	}
+	msg := strings.TrimSpace(string(data))
+	if len(msg) > 200 || strings.HasPrefix(msg, "<") || strings.HasPrefix(msg, "{") {
+		// An HTML page or unexpected JSON says nothing useful
+		return ""
+	}
+	return msg
+}
+
+// backoff returns the wait before retry number attempt: exponential from
+// baseBackoff, capped at maxBackoff, with the upper half jittered so
+// clients that failed together don't retry together.
+func backoff(attempt int) time.Duration {
+	d := baseBackoff << (attempt - 1)
+	if d > maxBackoff || d <= 0 {
+		d = maxBackoff
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// parseRetryAfter reads a Retry-After header in seconds or as an HTTP
+// date. It returns 0 if the header is missing or invalid.
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+func gzipJSON(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(v); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,58 @@
+package sender
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Identity is what the API reports about a token: the account it belongs
+// to, what it may do, and when it expires.
+type Identity struct {
+	Org       string     `json:"org"`
+	User      string     `json:"user,omitempty"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Expired reports whether the token is past its expiry.
+func (i *Identity) Expired() bool {
+	return i.ExpiresAt != nil && time.Now().After(*i.ExpiresAt)
+}
+
+// ErrInvalidToken is returned by Whoami when the API rejects the token.
+var ErrInvalidToken = errors.New("token rejected by the API")
+
+// Whoami validates token against the API at endpoint and returns the
+// identity it belongs to.
+func Whoami(endpoint, token, version string) (*Identity, error) {
+	req, err := http.NewRequest("GET", endpoint+"/v1/auth/whoami", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("User-Agent", "tempo-cli/"+version)
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("API unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return nil, fmt.Errorf("%w (%d)", ErrInvalidToken, resp.StatusCode)
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return nil, fmt.Errorf("API returned %d", resp.StatusCode)
+	}
+
+	var id Identity
+	if err := json.NewDecoder(resp.Body).Decode(&id); err != nil {
+		return nil, fmt.Errorf("decoding whoami response: %w", err)
+	}
+	return &id, nil
+}
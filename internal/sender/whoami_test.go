@@ -0,0 +1,54 @@
+package sender
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWhoami(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/auth/whoami" {
+			http.NotFound(w, r)
+			return
+		}
+		switch r.Header.Get("Authorization") {
+		case "Bearer tpo_good":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"org": "acme", "user": "jane@acme.dev", "scopes": ["attributions:write"], "expires_at": "2030-01-02T03:04:05Z"}`))
+		case "Bearer tpo_broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer srv.Close()
+
+	id, err := Whoami(srv.URL, "tpo_good", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.Org != "acme" || id.User != "jane@acme.dev" || len(id.Scopes) != 1 || id.Scopes[0] != "attributions:write" {
+		t.Errorf("got %+v", id)
+	}
+	if id.ExpiresAt == nil || !id.ExpiresAt.Equal(time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Errorf("expires_at: got %v", id.ExpiresAt)
+	}
+	if id.Expired() {
+		t.Error("token should not be expired")
+	}
+
+	if _, err := Whoami(srv.URL, "tpo_revoked", "test"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("revoked token: got %v, want ErrInvalidToken", err)
+	}
+	if _, err := Whoami(srv.URL, "tpo_broken", "test"); err == nil || errors.Is(err, ErrInvalidToken) {
+		t.Errorf("server error: got %v, want a non-token error", err)
+	}
+
+	srv.Close()
+	if _, err := Whoami(srv.URL, "tpo_good", "test"); err == nil || errors.Is(err, ErrInvalidToken) {
+		t.Errorf("unreachable: got %v, want a non-token error", err)
+	}
+}
@@ -0,0 +1,141 @@
+package sqlite
+
+import (
+	"strings"
+)
+
+// Minimal CREATE TABLE / CREATE INDEX parsing: just enough to name columns
+// and tell which columns an index covers.
+
+// tableColumns returns the column names declared in a CREATE TABLE statement.
+func tableColumns(sql string) []string {
+	var cols []string
+	for _, def := range splitDefs(sql) {
+		name := firstIdent(def)
+		if name == "" || isTableConstraint(name) {
+			continue
+		}
+		cols = append(cols, name)
+	}
+	return cols
+}
+
+// uniqueColumns returns the columns of each UNIQUE or non-rowid PRIMARY KEY
+// constraint in declaration order, matching SQLite's numbering of
+// sqlite_autoindex_<table>_<n> indexes.
+func uniqueColumns(sql string) [][]string {
+	var result [][]string
+	for _, def := range splitDefs(sql) {
+		name := firstIdent(def)
+		upper := strings.ToUpper(def)
+		if isTableConstraint(name) {
+			if strings.Contains(upper, "UNIQUE") || strings.Contains(upper, "PRIMARY KEY") {
+				if cols := parenList(def); len(cols) > 0 {
+					result = append(result, cols)
+				}
+			}
+			continue
+		}
+		isIntPK := strings.Contains(upper, "PRIMARY KEY") && strings.Contains(upper, " INTEGER")
+		if strings.Contains(upper, "PRIMARY KEY") && !isIntPK {
+			result = append(result, []string{name})
+		}
+		if strings.Contains(upper, "UNIQUE") {
+			result = append(result, []string{name})
+		}
+	}
+	return result
+}
+
+// indexColumns returns the indexed columns of a CREATE INDEX statement, or
+// nil for automatic indexes (which have no SQL).
+func indexColumns(sql string) []string {
+	if sql == "" {
+		return nil
+	}
+	return parenList(sql)
+}
+
+// splitDefs returns the comma-separated definitions inside the outermost
+// parentheses of a CREATE TABLE statement.
+func splitDefs(sql string) []string {
+	start := strings.Index(sql, "(")
+	end := strings.LastIndex(sql, ")")
+	if start < 0 || end <= start {
+		return nil
+	}
+	body := sql[start+1 : end]
+
+	var defs []string
+	depth, last := 0, 0
+	var quote byte
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`' || c == '[':
+			quote = c
+			if c == '[' {
+				quote = ']'
+			}
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			defs = append(defs, strings.TrimSpace(body[last:i]))
+			last = i + 1
+		}
+	}
+	defs = append(defs, strings.TrimSpace(body[last:]))
+	return defs
+}
+
+// parenList returns the identifiers listed in the first parenthesized group.
+func parenList(s string) []string {
+	start := strings.Index(s, "(")
+	end := strings.Index(s, ")")
+	if start < 0 || end <= start {
+		return nil
+	}
+	var cols []string
+	for _, part := range strings.Split(s[start+1:end], ",") {
+		if name := firstIdent(strings.TrimSpace(part)); name != "" {
+			cols = append(cols, name)
+		}
+	}
+	return cols
+}
+
+// firstIdent returns the first identifier in s with any quoting removed.
+func firstIdent(s string) string {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return ""
+	}
+	switch s[0] {
+	case '"', '`', '\'':
+		if end := strings.IndexByte(s[1:], s[0]); end >= 0 {
+			return s[1 : end+1]
+		}
+	case '[':
+		if end := strings.IndexByte(s, ']'); end >= 0 {
+			return s[1:end]
+		}
+	}
+	if i := strings.IndexAny(s, " \t\n\r("); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+func isTableConstraint(word string) bool {
+	switch strings.ToUpper(word) {
+	case "CONSTRAINT", "PRIMARY", "UNIQUE", "CHECK", "FOREIGN":
+		return true
+	}
+	return false
+}
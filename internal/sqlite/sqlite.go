@@ -0,0 +1,609 @@
+// Package sqlite is a minimal, read-only reader for SQLite database files.
+//
+// It walks the on-disk B-tree format directly so the CLI can read editor state
+// databases (VS Code / Cursor state.vscdb) without cgo or the sqlite3 binary.
+// Only what those databases need is supported: UTF-8 text, table and index
+// B-trees, overflow pages and committed frames from a -wal file. There is no
+// SQL: callers look tables up by name and read rows by key.
+//
+// File format reference: https://www.sqlite.org/fileformat.html
+package sqlite
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+)
+
+const headerMagic = "SQLite format 3\x00"
+
+// maxDepth bounds B-tree descent so corrupt page links cannot loop forever.
+const maxDepth = 64
+
+// B-tree page types.
+const (
+	pageIndexInterior = 0x02
+	pageTableInterior = 0x05
+	pageIndexLeaf     = 0x0a
+	pageTableLeaf     = 0x0d
+)
+
+// ErrNotFound is returned when a table or row does not exist.
+var ErrNotFound = errors.New("sqlite: not found")
+
+// errStop ends a B-tree walk early without reporting an error.
+var errStop = errors.New("stop")
+
+// DB is an open, read-only SQLite database file.
+type DB struct {
+	f        *os.File
+	pageSize int
+	usable   int
+	wal      *walIndex
+}
+
+// Table describes a table from the schema.
+type Table struct {
+	db      *DB
+	Name    string
+	Columns []string
+	root    uint32
+	indexes []index
+}
+
+type index struct {
+	name    string
+	columns []string
+	root    uint32
+}
+
+// Row is a decoded table row. Values are nil, int64, float64, string or []byte.
+type Row struct {
+	RowID  int64
+	Values []any
+}
+
+// Open opens the database at path for reading. Committed frames from a
+// "-wal" file next to it are applied on top of the main file.
+func Open(path string) (*DB, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var hdr [100]byte
+	if _, err := f.ReadAt(hdr[:], 0); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("sqlite: reading header: %w", err)
+	}
+	if string(hdr[:16]) != headerMagic {
+		f.Close()
+		return nil, fmt.Errorf("sqlite: %s is not a database file", path)
+	}
+	if enc := binary.BigEndian.Uint32(hdr[56:60]); enc != 0 && enc != 1 {
+		f.Close()
+		return nil, fmt.Errorf("sqlite: unsupported text encoding %d", enc)
+	}
+
+	pageSize := int(binary.BigEndian.Uint16(hdr[16:18]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	if pageSize < 512 || pageSize&(pageSize-1) != 0 {
+		f.Close()
+		return nil, fmt.Errorf("sqlite: invalid page size %d", pageSize)
+	}
+
+	db := &DB{
+		f:        f,
+		pageSize: pageSize,
+		usable:   pageSize - int(hdr[20]),
+	}
+	if wal, err := openWAL(path+"-wal", pageSize); err == nil {
+		db.wal = wal
+	}
+	return db, nil
+}
+
+// Close releases the database file handles.
+func (db *DB) Close() error {
+	if db.wal != nil {
+		db.wal.f.Close()
+	}
+	return db.f.Close()
+}
+
+// readPage returns the contents of page n (1-based), preferring the latest
+// committed copy in the WAL.
+func (db *DB) readPage(n uint32) ([]byte, error) {
+	if n == 0 {
+		return nil, fmt.Errorf("sqlite: invalid page number 0")
+	}
+	buf := make([]byte, db.pageSize)
+	if db.wal != nil {
+		if off, ok := db.wal.frames[n]; ok {
+			if _, err := db.wal.f.ReadAt(buf, off); err != nil {
+				return nil, err
+			}
+			return buf, nil
+		}
+	}
+	if _, err := db.f.ReadAt(buf, int64(n-1)*int64(db.pageSize)); err != nil {
+		return nil, fmt.Errorf("sqlite: reading page %d: %w", n, err)
+	}
+	return buf, nil
+}
+
+// Table looks up a table and its indexes in the schema.
+func (db *DB) Table(name string) (t *Table, err error) {
+	defer recoverCorrupt(&err)
+
+	var sql string
+	var found bool
+	var indexes []index
+	err = db.walkTable(1, 0, func(r Row) error {
+		if len(r.Values) < 5 {
+			return nil
+		}
+		typ, _ := r.Values[0].(string)
+		objName, _ := r.Values[1].(string)
+		tblName, _ := r.Values[2].(string)
+		root, _ := r.Values[3].(int64)
+		objSQL, _ := r.Values[4].(string)
+		if !strings.EqualFold(tblName, name) {
+			return nil
+		}
+		switch typ {
+		case "table":
+			t = &Table{db: db, Name: objName, root: uint32(root)}
+			sql = objSQL
+			found = true
+		case "index":
+			indexes = append(indexes, index{name: objName, columns: indexColumns(objSQL), root: uint32(root)})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: table %s", ErrNotFound, name)
+	}
+
+	t.Columns = tableColumns(sql)
+	unique := uniqueColumns(sql)
+	for _, idx := range indexes {
+		// Automatic indexes have no SQL; they back UNIQUE and PRIMARY KEY
+		// constraints, numbered in declaration order.
+		if idx.columns == nil && strings.HasPrefix(idx.name, "sqlite_autoindex_") {
+			var n int
+			if _, err := fmt.Sscanf(idx.name[strings.LastIndex(idx.name, "_")+1:], "%d", &n); err == nil &&
+				n >= 1 && n <= len(unique) {
+				idx.columns = unique[n-1]
+			}
+		}
+		t.indexes = append(t.indexes, idx)
+	}
+	return t, nil
+}
+
+// Scan calls fn for every row of the table in rowid order.
+func (t *Table) Scan(fn func(Row) error) (err error) {
+	defer recoverCorrupt(&err)
+	err = t.db.walkTable(t.root, 0, fn)
+	if errors.Is(err, errStop) {
+		return nil
+	}
+	return err
+}
+
+// Lookup returns the first row whose column equals value.
+func (t *Table) Lookup(column string, value string) (Row, error) {
+	var result *Row
+	err := t.ScanRange(column, value, value+"\x00", func(r Row) error {
+		result = &r
+		return errStop
+	})
+	if err != nil {
+		return Row{}, err
+	}
+	if result == nil {
+		return Row{}, ErrNotFound
+	}
+	return *result, nil
+}
+
+// ScanPrefix calls fn for every row whose text column starts with prefix.
+func (t *Table) ScanPrefix(column string, prefix string, fn func(Row) error) error {
+	return t.ScanRange(column, prefix, prefixEnd(prefix), fn)
+}
+
+// ScanRange calls fn for every row whose text column value v satisfies
+// lo <= v < hi (binary collation). An empty hi means no upper bound. When an
+// index on the column exists it is used; otherwise the table is scanned.
+func (t *Table) ScanRange(column string, lo, hi string, fn func(Row) error) (err error) {
+	defer recoverCorrupt(&err)
+
+	col := t.columnIndex(column)
+	if col < 0 {
+		return fmt.Errorf("sqlite: table %s has no column %s", t.Name, column)
+	}
+
+	inRange := func(v any) bool {
+		return compareText(v, lo) >= 0 && (hi == "" || compareText(v, hi) < 0)
+	}
+
+	for _, idx := range t.indexes {
+		if len(idx.columns) == 0 || !strings.EqualFold(idx.columns[0], column) {
+			continue
+		}
+		err = t.db.walkIndexRange(idx.root, lo, hi, 0, func(rec []any) error {
+			if len(rec) < 2 {
+				return nil
+			}
+			rowid, ok := rec[len(rec)-1].(int64)
+			if !ok {
+				return nil
+			}
+			row, err := t.db.tableRow(t.root, rowid, 0)
+			if err != nil {
+				if errors.Is(err, ErrNotFound) {
+					return nil
+				}
+				return err
+			}
+			if col < len(row.Values) && inRange(row.Values[col]) {
+				return fn(row)
+			}
+			return nil
+		})
+		if errors.Is(err, errStop) {
+			return nil
+		}
+		return err
+	}
+
+	err = t.db.walkTable(t.root, 0, func(r Row) error {
+		if col < len(r.Values) && inRange(r.Values[col]) {
+			return fn(r)
+		}
+		return nil
+	})
+	if errors.Is(err, errStop) {
+		return nil
+	}
+	return err
+}
+
+func (t *Table) columnIndex(column string) int {
+	for i, c := range t.Columns {
+		if strings.EqualFold(c, column) {
+			return i
+		}
+	}
+	return -1
+}
+
+// walkTable visits every row of the table B-tree rooted at page in order.
+func (db *DB) walkTable(page uint32, depth int, fn func(Row) error) error {
+	if depth > maxDepth {
+		return fmt.Errorf("sqlite: B-tree too deep at page %d", page)
+	}
+	data, err := db.readPage(page)
+	if err != nil {
+		return err
+	}
+	hdr := btreeHeaderOffset(page)
+	cells := cellPointers(data, hdr)
+
+	switch data[hdr] {
+	case pageTableLeaf:
+		for _, off := range cells {
+			payloadSize, n := readVarint(data[off:])
+			off += n
+			rowid, n := readVarint(data[off:])
+			off += n
+			payload, err := db.payload(data, off, payloadSize, true)
+			if err != nil {
+				return err
+			}
+			values, err := decodeRecord(payload)
+			if err != nil {
+				return err
+			}
+			if err := fn(Row{RowID: rowid, Values: values}); err != nil {
+				return err
+			}
+		}
+		return nil
+	case pageTableInterior:
+		for _, off := range cells {
+			child := binary.BigEndian.Uint32(data[off:])
+			if err := db.walkTable(child, depth+1, fn); err != nil {
+				return err
+			}
+		}
+		return db.walkTable(binary.BigEndian.Uint32(data[hdr+8:]), depth+1, fn)
+	}
+	return fmt.Errorf("sqlite: page %d is not a table page (type %d)", page, data[hdr])
+}
+
+// tableRow finds the row with the given rowid in the table B-tree rooted at page.
+func (db *DB) tableRow(page uint32, rowid int64, depth int) (Row, error) {
+	if depth > maxDepth {
+		return Row{}, fmt.Errorf("sqlite: B-tree too deep at page %d", page)
+	}
+	data, err := db.readPage(page)
+	if err != nil {
+		return Row{}, err
+	}
+	hdr := btreeHeaderOffset(page)
+	cells := cellPointers(data, hdr)
+
+	switch data[hdr] {
+	case pageTableLeaf:
+		for _, off := range cells {
+			payloadSize, n := readVarint(data[off:])
+			off += n
+			id, n := readVarint(data[off:])
+			off += n
+			if id != rowid {
+				continue
+			}
+			payload, err := db.payload(data, off, payloadSize, true)
+			if err != nil {
+				return Row{}, err
+			}
+			values, err := decodeRecord(payload)
+			if err != nil {
+				return Row{}, err
+			}
+			return Row{RowID: id, Values: values}, nil
+		}
+		return Row{}, ErrNotFound
+	case pageTableInterior:
+		for _, off := range cells {
+			key, _ := readVarint(data[off+4:])
+			if rowid <= key {
+				return db.tableRow(binary.BigEndian.Uint32(data[off:]), rowid, depth+1)
+			}
+		}
+		return db.tableRow(binary.BigEndian.Uint32(data[hdr+8:]), rowid, depth+1)
+	}
+	return Row{}, fmt.Errorf("sqlite: page %d is not a table page (type %d)", page, data[hdr])
+}
+
+// walkIndexRange visits index records whose first column lies in [lo, hi),
+// skipping subtrees that fall entirely below lo and stopping past hi.
+func (db *DB) walkIndexRange(page uint32, lo, hi string, depth int, fn func([]any) error) error {
+	if depth > maxDepth {
+		return fmt.Errorf("sqlite: B-tree too deep at page %d", page)
+	}
+	data, err := db.readPage(page)
+	if err != nil {
+		return err
+	}
+	hdr := btreeHeaderOffset(page)
+	cells := cellPointers(data, hdr)
+	interior := data[hdr] == pageIndexInterior
+	if !interior && data[hdr] != pageIndexLeaf {
+		return fmt.Errorf("sqlite: page %d is not an index page (type %d)", page, data[hdr])
+	}
+
+	for _, off := range cells {
+		var child uint32
+		if interior {
+			child = binary.BigEndian.Uint32(data[off:])
+			off += 4
+		}
+		payloadSize, n := readVarint(data[off:])
+		off += n
+		payload, err := db.payload(data, off, payloadSize, false)
+		if err != nil {
+			return err
+		}
+		rec, err := decodeRecord(payload)
+		if err != nil {
+			return err
+		}
+		var key any
+		if len(rec) > 0 {
+			key = rec[0]
+		}
+
+		below := compareText(key, lo) < 0
+		if interior && !below {
+			if err := db.walkIndexRange(child, lo, hi, depth+1, fn); err != nil {
+				return err
+			}
+		}
+		if hi != "" && compareText(key, hi) >= 0 {
+			return errStop
+		}
+		if !below {
+			if err := fn(rec); err != nil {
+				return err
+			}
+		}
+	}
+	if interior {
+		return db.walkIndexRange(binary.BigEndian.Uint32(data[hdr+8:]), lo, hi, depth+1, fn)
+	}
+	return nil
+}
+
+// payload returns the full cell payload starting at off, following the
+// overflow page chain when it does not fit on the page.
+func (db *DB) payload(page []byte, off int, size int64, table bool) ([]byte, error) {
+	u := db.usable
+	maxLocal := u - 35
+	if !table {
+		maxLocal = (u-12)*64/255 - 23
+	}
+	if size <= int64(maxLocal) {
+		return page[off : off+int(size)], nil
+	}
+
+	minLocal := (u-12)*32/255 - 23
+	local := minLocal + int((size-int64(minLocal))%int64(u-4))
+	if local > maxLocal {
+		local = minLocal
+	}
+
+	out := make([]byte, 0, size)
+	out = append(out, page[off:off+local]...)
+	next := binary.BigEndian.Uint32(page[off+local:])
+	for hops := 0; int64(len(out)) < size; hops++ {
+		if next == 0 || hops > 1<<20 {
+			return nil, fmt.Errorf("sqlite: truncated overflow chain")
+		}
+		ovf, err := db.readPage(next)
+		if err != nil {
+			return nil, err
+		}
+		n := int(size) - len(out)
+		if n > u-4 {
+			n = u - 4
+		}
+		out = append(out, ovf[4:4+n]...)
+		next = binary.BigEndian.Uint32(ovf)
+	}
+	return out, nil
+}
+
+// btreeHeaderOffset returns where the B-tree header starts; page 1 begins
+// with the 100-byte database header.
+func btreeHeaderOffset(page uint32) int {
+	if page == 1 {
+		return 100
+	}
+	return 0
+}
+
+// cellPointers returns the cell offsets of a B-tree page.
+func cellPointers(data []byte, hdr int) []int {
+	n := int(binary.BigEndian.Uint16(data[hdr+3:]))
+	ptrStart := hdr + 8
+	if data[hdr] == pageIndexInterior || data[hdr] == pageTableInterior {
+		ptrStart = hdr + 12
+	}
+	cells := make([]int, 0, n)
+	for i := 0; i < n; i++ {
+		cells = append(cells, int(binary.BigEndian.Uint16(data[ptrStart+2*i:])))
+	}
+	return cells
+}
+
+// readVarint decodes a SQLite variable-length integer and returns it with
+// the number of bytes consumed.
+func readVarint(b []byte) (int64, int) {
+	var v uint64
+	for i := 0; i < 8; i++ {
+		v = v<<7 | uint64(b[i]&0x7f)
+		if b[i]&0x80 == 0 {
+			return int64(v), i + 1
+		}
+	}
+	v = v<<8 | uint64(b[8])
+	return int64(v), 9
+}
+
+// decodeRecord decodes a record into its column values.
+func decodeRecord(b []byte) ([]any, error) {
+	hdrSize, n := readVarint(b)
+	if hdrSize < int64(n) || hdrSize > int64(len(b)) {
+		return nil, fmt.Errorf("sqlite: invalid record header")
+	}
+	var types []int64
+	for pos := n; pos < int(hdrSize); {
+		t, n := readVarint(b[pos:])
+		types = append(types, t)
+		pos += n
+	}
+
+	values := make([]any, 0, len(types))
+	body := b[hdrSize:]
+	for _, t := range types {
+		var size int
+		switch {
+		case t == 0, t == 8, t == 9:
+			size = 0
+		case t >= 1 && t <= 4:
+			size = int(t)
+		case t == 5:
+			size = 6
+		case t == 6, t == 7:
+			size = 8
+		case t >= 12:
+			size = int(t-12) / 2
+		default:
+			return nil, fmt.Errorf("sqlite: invalid serial type %d", t)
+		}
+		if size > len(body) {
+			return nil, fmt.Errorf("sqlite: record value overruns payload")
+		}
+		v := body[:size]
+		body = body[size:]
+
+		switch {
+		case t == 0:
+			values = append(values, nil)
+		case t == 8:
+			values = append(values, int64(0))
+		case t == 9:
+			values = append(values, int64(1))
+		case t <= 6:
+			var x int64
+			for _, c := range v {
+				x = x<<8 | int64(c)
+			}
+			// Sign-extend from the stored width
+			shift := 64 - 8*uint(size)
+			values = append(values, x<<shift>>shift)
+		case t == 7:
+			values = append(values, math.Float64frombits(binary.BigEndian.Uint64(v)))
+		case t%2 == 0:
+			values = append(values, bytes.Clone(v))
+		default:
+			values = append(values, string(v))
+		}
+	}
+	return values, nil
+}
+
+// compareText orders a value against a text bound using SQLite's ordering:
+// NULL and numbers sort before text, blobs after.
+func compareText(v any, s string) int {
+	switch x := v.(type) {
+	case string:
+		return strings.Compare(x, s)
+	case []byte:
+		return 1
+	default:
+		return -1
+	}
+}
+
+// prefixEnd returns the smallest string greater than every string with the
+// given prefix, or "" if there is none.
+func prefixEnd(prefix string) string {
+	b := []byte(prefix)
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] < 0xff {
+			b[i]++
+			return string(b[:i+1])
+		}
+	}
+	return ""
+}
+
+// recoverCorrupt turns out-of-range panics from malformed pages into errors.
+func recoverCorrupt(err *error) {
+	if r := recover(); r != nil {
+		*err = fmt.Errorf("sqlite: corrupt database: %v", r)
+	}
+}
@@ -0,0 +1,267 @@
+package sqlite
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// skipIfNoSQLite skips the test if the sqlite3 CLI (used to build fixtures)
+// is not available.
+func skipIfNoSQLite(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skip("sqlite3 not found, skipping fixture-based test")
+	}
+}
+
+// createTestDB runs a SQL script against a new database at dbPath.
+func createTestDB(t *testing.T, dbPath, script string) {
+	t.Helper()
+	cmd := exec.Command("sqlite3", dbPath)
+	cmd.Stdin = strings.NewReader(script)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("sqlite3 failed: %v\n%s", err, out)
+	}
+}
+
+const kvSchema = `CREATE TABLE ItemTable (key TEXT UNIQUE ON CONFLICT REPLACE, value BLOB);`
+
+func TestLookupAndScanPrefix(t *testing.T) {
+	skipIfNoSQLite(t)
+	dbPath := filepath.Join(t.TempDir(), "state.vscdb")
+	createTestDB(t, dbPath, kvSchema+`
+INSERT INTO ItemTable VALUES ('composer.composerData', '{"allComposers":[]}');
+INSERT INTO ItemTable VALUES ('bubbleId:a:1', 'a1');
+INSERT INTO ItemTable VALUES ('bubbleId:a:2', 'a2');
+INSERT INTO ItemTable VALUES ('bubbleId:ab:1', 'ab1');
+INSERT INTO ItemTable VALUES ('bubbleId:b:1', CAST('b1' AS BLOB));
+`)
+
+	db, err := Open(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	table, err := db.Table("ItemTable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(table.Columns, ","); got != "key,value" {
+		t.Errorf("columns: got %q", got)
+	}
+
+	row, err := table.Lookup("key", "composer.composerData")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if row.Values[1] != `{"allComposers":[]}` {
+		t.Errorf("value: got %v", row.Values[1])
+	}
+
+	if _, err := table.Lookup("key", "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	var keys []string
+	err = table.ScanPrefix("key", "bubbleId:a:", func(r Row) error {
+		keys = append(keys, r.Values[0].(string))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(keys, ",") != "bubbleId:a:1,bubbleId:a:2" {
+		t.Errorf("prefix scan: got %v", keys)
+	}
+
+	row, err = table.Lookup("key", "bubbleId:b:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, ok := row.Values[1].([]byte); !ok || string(b) != "b1" {
+		t.Errorf("blob value: got %#v", row.Values[1])
+	}
+}
+
+func TestManyRowsAndOverflow(t *testing.T) {
+	skipIfNoSQLite(t)
+	dbPath := filepath.Join(t.TempDir(), "state.vscdb")
+	// 5000 rows force interior pages in both the table and its index; the
+	// 200KB value spans a chain of overflow pages.
+	createTestDB(t, dbPath, kvSchema+`
+WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i+1 FROM n WHERE i < 5000)
+INSERT INTO ItemTable SELECT printf('row:%05d', i), printf('value-%d', i) FROM n;
+INSERT INTO ItemTable VALUES ('big', printf('%.*c', 200000, 'x'));
+INSERT INTO ItemTable VALUES (printf('%.*c', 3000, 'k'), 'long key');
+`)
+
+	db, err := Open(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	table, err := db.Table("ItemTable")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count := 0
+	if err := table.Scan(func(Row) error { count++; return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if count != 5002 {
+		t.Errorf("scan: got %d rows, want 5002", count)
+	}
+
+	row, err := table.Lookup("key", "row:04321")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if row.Values[1] != "value-4321" {
+		t.Errorf("lookup: got %v", row.Values[1])
+	}
+
+	row, err = table.Lookup("key", "big")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := row.Values[1].(string); len(s) != 200000 || strings.Trim(s, "x") != "" {
+		t.Errorf("overflow value: got length %d", len(s))
+	}
+
+	row, err = table.Lookup("key", strings.Repeat("k", 3000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if row.Values[1] != "long key" {
+		t.Errorf("overflowing index key: got %v", row.Values[1])
+	}
+
+	var keys []string
+	err = table.ScanPrefix("key", "row:0499", func(r Row) error {
+		keys = append(keys, r.Values[0].(string))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 10 || keys[0] != "row:04990" || keys[9] != "row:04999" {
+		t.Errorf("prefix scan: got %v", keys)
+	}
+}
+
+func TestTableScanWithoutIndex(t *testing.T) {
+	skipIfNoSQLite(t)
+	dbPath := filepath.Join(t.TempDir(), "plain.db")
+	createTestDB(t, dbPath, `
+CREATE TABLE t (id INTEGER PRIMARY KEY, name TEXT, score REAL, n INTEGER);
+INSERT INTO t VALUES (1, 'alpha', 1.5, -3);
+INSERT INTO t VALUES (2, 'beta', NULL, 70000);
+INSERT INTO t VALUES (3, 'alphabet', 2.0, -9000000000);
+`)
+
+	db, err := Open(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	table, err := db.Table("t")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rows []Row
+	err = table.ScanPrefix("name", "alpha", func(r Row) error {
+		rows = append(rows, r)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(rows))
+	}
+	if rows[0].RowID != 1 || rows[0].Values[2] != 1.5 || rows[0].Values[3] != int64(-3) {
+		t.Errorf("row 1: got %+v", rows[0])
+	}
+	if rows[1].Values[3] != int64(-9000000000) {
+		t.Errorf("row 3: got %+v", rows[1])
+	}
+
+	if _, err := db.Table("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestWALFrames(t *testing.T) {
+	skipIfNoSQLite(t)
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "live.db")
+	snap := filepath.Join(dir, "snap.db")
+
+	// Copy the database and its WAL while the connection is still open, so
+	// the snapshot's newest rows exist only in the -wal file.
+	createTestDB(t, dbPath, `PRAGMA journal_mode=WAL;
+PRAGMA wal_autocheckpoint=0;
+`+kvSchema+`
+INSERT INTO ItemTable VALUES ('k1', 'v1');
+INSERT INTO ItemTable VALUES ('k2', 'v2');
+.system cp `+dbPath+` `+snap+`
+.system cp `+dbPath+`-wal `+snap+`-wal
+`)
+	if _, err := os.Stat(snap + "-wal"); err != nil {
+		t.Skip("sqlite3 did not leave a WAL to copy")
+	}
+
+	db, err := Open(snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	table, err := db.Table("ItemTable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	row, err := table.Lookup("key", "k2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if row.Values[1] != "v2" {
+		t.Errorf("got %v", row.Values[1])
+	}
+}
+
+func TestOpen_NotADatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "junk.db")
+	if err := os.WriteFile(path, []byte(strings.Repeat("x", 200)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(path); err == nil {
+		t.Error("expected error for non-database file")
+	}
+}
+
+func TestUniqueColumns(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{`CREATE TABLE ItemTable (key TEXT UNIQUE ON CONFLICT REPLACE, value BLOB)`, "key"},
+		{`CREATE TABLE t (id INTEGER PRIMARY KEY, a TEXT, b TEXT, UNIQUE (a, b))`, "a+b"},
+		{`CREATE TABLE t ("k" TEXT PRIMARY KEY, v BLOB)`, "k"},
+	}
+	for _, tt := range tests {
+		var parts []string
+		for _, cols := range uniqueColumns(tt.sql) {
+			parts = append(parts, strings.Join(cols, "+"))
+		}
+		if got := strings.Join(parts, ";"); got != tt.want {
+			t.Errorf("uniqueColumns(%q) = %q, want %q", tt.sql, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package sqlite
+
+import (
+	"encoding/binary"
+	"io"
+	"os"
+)
+
+// walIndex maps page numbers to the file offset of their latest committed
+// frame in a write-ahead log.
+//
+// WAL layout: a 32-byte header (magic, version, page size, checkpoint
+// sequence, salts, checksum) followed by frames of a 24-byte header (page
+// number, database size for commit frames, salts, checksum) plus one page.
+// Frames count only if their salts match the header, their cumulative
+// checksum is valid, and a later commit frame covers them.
+type walIndex struct {
+	f      *os.File
+	frames map[uint32]int64
+}
+
+const (
+	walHeaderSize      = 32
+	walFrameHeaderSize = 24
+)
+
+// openWAL reads the WAL at path. It returns an error if the file is missing,
+// empty or does not belong to a database with the given page size.
+func openWAL(path string, pageSize int) (*walIndex, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var hdr [walHeaderSize]byte
+	if _, err := io.ReadFull(f, hdr[:]); err != nil {
+		f.Close()
+		return nil, err
+	}
+	magic := binary.BigEndian.Uint32(hdr[0:4])
+	if magic != 0x377f0682 && magic != 0x377f0683 {
+		f.Close()
+		return nil, os.ErrInvalid
+	}
+	if int(binary.BigEndian.Uint32(hdr[8:12])) != pageSize {
+		f.Close()
+		return nil, os.ErrInvalid
+	}
+	order := binary.ByteOrder(binary.LittleEndian)
+	if magic&1 == 1 {
+		order = binary.BigEndian
+	}
+
+	s0, s1 := walChecksum(order, hdr[:24], 0, 0)
+	if s0 != binary.BigEndian.Uint32(hdr[24:28]) || s1 != binary.BigEndian.Uint32(hdr[28:32]) {
+		f.Close()
+		return nil, os.ErrInvalid
+	}
+	salt1 := binary.BigEndian.Uint32(hdr[16:20])
+	salt2 := binary.BigEndian.Uint32(hdr[20:24])
+
+	w := &walIndex{f: f, frames: make(map[uint32]int64)}
+	pending := make(map[uint32]int64)
+	frame := make([]byte, walFrameHeaderSize+pageSize)
+	for off := int64(walHeaderSize); ; off += int64(len(frame)) {
+		if _, err := f.ReadAt(frame, off); err != nil {
+			break
+		}
+		fh := frame[:walFrameHeaderSize]
+		if binary.BigEndian.Uint32(fh[8:12]) != salt1 || binary.BigEndian.Uint32(fh[12:16]) != salt2 {
+			break
+		}
+		s0, s1 = walChecksum(order, fh[:8], s0, s1)
+		s0, s1 = walChecksum(order, frame[walFrameHeaderSize:], s0, s1)
+		if s0 != binary.BigEndian.Uint32(fh[16:20]) || s1 != binary.BigEndian.Uint32(fh[20:24]) {
+			break
+		}
+
+		pending[binary.BigEndian.Uint32(fh[0:4])] = off + walFrameHeaderSize
+		if binary.BigEndian.Uint32(fh[4:8]) != 0 {
+			// Commit frame: everything up to here is durable
+			for pg, o := range pending {
+				w.frames[pg] = o
+			}
+			clear(pending)
+		}
+	}
+
+	if len(w.frames) == 0 {
+		f.Close()
+		return nil, os.ErrNotExist
+	}
+	return w, nil
+}
+
+// walChecksum extends the WAL's cumulative Fletcher-style checksum over b,
+// whose length must be a multiple of 8.
+func walChecksum(order binary.ByteOrder, b []byte, s0, s1 uint32) (uint32, uint32) {
+	for i := 0; i+8 <= len(b); i += 8 {
+		s0 += order.Uint32(b[i:]) + s1
+		s1 += order.Uint32(b[i+4:]) + s0
+	}
+	return s0, s1
+}